@@ -0,0 +1,134 @@
+package crawler
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AuthConfig holds the credentials sent to the hosts matching a host rule
+type AuthConfig struct {
+
+	// Basic auth user name and password, or the environment variable holding the password
+	Username    string `json:"username"`
+	Password    string `json:"password"`
+	PasswordEnv string `json:"password_env"`
+
+	// Bearer token, or the environment variable holding it
+	Token    string `json:"token"`
+	TokenEnv string `json:"token_env"`
+
+	// Cookies sent with every request, by name
+	Cookies map[string]string `json:"cookies"`
+}
+
+// Keeps credentials out of logs and reports even if the config gets printed
+func (a *AuthConfig) String() string {
+	return "[credentials redacted]"
+}
+
+// Fills in the secrets that come from environment variables
+func (a *AuthConfig) resolve() error {
+	if a.PasswordEnv != "" {
+		a.Password = os.Getenv(a.PasswordEnv)
+		if a.Password == "" {
+			return fmt.Errorf("environment variable %s is not set", a.PasswordEnv)
+		}
+	}
+
+	if a.TokenEnv != "" {
+		a.Token = os.Getenv(a.TokenEnv)
+		if a.Token == "" {
+			return fmt.Errorf("environment variable %s is not set", a.TokenEnv)
+		}
+	}
+
+	return nil
+}
+
+// Adds the credentials to a request
+func (a *AuthConfig) apply(req *http.Request) {
+	if a.Username != "" {
+		req.SetBasicAuth(a.Username, a.Password)
+	}
+
+	if a.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+a.Token)
+	}
+
+	for name, value := range a.Cookies {
+		req.AddCookie(&http.Cookie{Name: name, Value: value})
+	}
+}
+
+// Function to create a cookie jar filled from a Netscape format cookie file (as written by curl or browsers)
+func loadCookieJar(path string) (http.CookieJar, error) {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+
+		// HttpOnly cookies are written as comments with a special prefix
+		line = strings.TrimPrefix(line, "#HttpOnly_")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		// domain, include subdomains, path, secure, expiry, name, value
+		fields := strings.Split(line, "\t")
+		if len(fields) != 7 {
+			return nil, fmt.Errorf("%s:%d: expected 7 tab separated fields, got %d", path, lineNumber, len(fields))
+		}
+
+		expiry, err := strconv.ParseInt(fields[4], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: bad expiry: %w", path, lineNumber, err)
+		}
+
+		host := strings.TrimPrefix(fields[0], ".")
+		cookie := &http.Cookie{
+			Path:   fields[2],
+			Secure: fields[3] == "TRUE",
+			Name:   fields[5],
+			Value:  fields[6],
+		}
+
+		// An expiry of 0 marks a session cookie
+		if expiry > 0 {
+			cookie.Expires = time.Unix(expiry, 0)
+		}
+
+		// Only cookies that include subdomains carry a domain, the rest are host-only
+		if fields[1] == "TRUE" {
+			cookie.Domain = host
+		}
+
+		scheme := "http"
+		if cookie.Secure {
+			scheme = "https"
+		}
+		jar.SetCookies(&url.URL{Scheme: scheme, Host: host, Path: cookie.Path}, []*http.Cookie{cookie})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return jar, nil
+}
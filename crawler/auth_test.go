@@ -0,0 +1,168 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_hostTransport_auth(t *testing.T) {
+	// Records the credentials each server received
+	seen := map[string]string{}
+	record := func(name string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			cookie, _ := r.Cookie("session")
+			seen[name] = r.Header.Get("Authorization") + "|" + fmt.Sprint(cookie)
+		}
+	}
+
+	other := httptest.NewServer(record("other"))
+	defer other.Close()
+
+	private := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, other.URL, http.StatusFound)
+			return
+		}
+		record("private")(w, r)
+	}))
+	defer private.Close()
+
+	privateURL, _ := url.Parse(private.URL)
+
+	tests := []struct {
+		name        string
+		auth        *AuthConfig
+		path        string
+		wantPrivate string
+		wantOther   string
+	}{
+		{
+			name:        "Basic auth",
+			auth:        &AuthConfig{Username: "staff", Password: "hunter2"},
+			path:        "/",
+			wantPrivate: "Basic c3RhZmY6aHVudGVyMg==|",
+		},
+		{
+			name:        "Bearer token and cookie",
+			auth:        &AuthConfig{Token: "abc123", Cookies: map[string]string{"session": "s3cret"}},
+			path:        "/",
+			wantPrivate: "Bearer abc123|session=s3cret",
+		},
+		{
+			name:      "Credentials not forwarded on redirect to another host",
+			auth:      &AuthConfig{Token: "abc123", Cookies: map[string]string{"session": "s3cret"}},
+			path:      "/redirect",
+			wantOther: "|",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seen = map[string]string{}
+
+			cfg := DefaultConfig()
+			cfg.Hosts = []HostRule{{DomainGlob: privateURL.Host, Auth: tt.auth}, {DomainGlob: "*"}}
+			if err := cfg.compile(); err != nil {
+				t.Fatalf("compile() error = %v", err)
+			}
+
+			client, err := newHTTPClient(cfg)
+			if err != nil {
+				t.Fatalf("newHTTPClient() error = %v", err)
+			}
+
+			if _, err := getURLStatus(context.Background(), client, private.URL+tt.path); err != nil {
+				t.Fatalf("getURLStatus() error = %v", err)
+			}
+
+			if seen["private"] != tt.wantPrivate {
+				t.Errorf("private server saw %q, want %q", seen["private"], tt.wantPrivate)
+			}
+			if seen["other"] != tt.wantOther {
+				t.Errorf("other server saw %q, want %q", seen["other"], tt.wantOther)
+			}
+		})
+	}
+}
+
+func TestAuthConfig_resolve(t *testing.T) {
+	t.Setenv("KDLP_TEST_TOKEN", "from-env")
+
+	tests := []struct {
+		name      string
+		auth      *AuthConfig
+		wantToken string
+		wantErr   bool
+	}{
+		{name: "Token from environment", auth: &AuthConfig{TokenEnv: "KDLP_TEST_TOKEN"}, wantToken: "from-env"},
+		{name: "Missing environment variable", auth: &AuthConfig{PasswordEnv: "KDLP_TEST_UNSET"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.auth.resolve()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolve() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.auth.Token != tt.wantToken {
+				t.Errorf("resolve() token = %q, want %q", tt.auth.Token, tt.wantToken)
+			}
+		})
+	}
+}
+
+func TestAuthConfig_String(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Hosts[0].Auth = &AuthConfig{Username: "staff", Password: "hunter2", Token: "abc123"}
+
+	// Printing the config, e.g. while debugging, must not leak the secrets
+	printed := fmt.Sprintf("%v %+v", cfg, cfg.Hosts)
+	for _, secret := range []string{"hunter2", "abc123"} {
+		if strings.Contains(printed, secret) {
+			t.Errorf("printed config contains %q: %s", secret, printed)
+		}
+	}
+}
+
+func Test_loadCookieJar(t *testing.T) {
+	content := "# Netscape HTTP Cookie File\n" +
+		".kdlp.underground.software\tTRUE\t/\tTRUE\t0\tsession\tabc\n" +
+		"#HttpOnly_dash.kdlp.underground.software\tFALSE\t/\tFALSE\t0\ttoken\txyz\n"
+
+	path := filepath.Join(t.TempDir(), "cookies.txt")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("Error writing cookie file: %v", err)
+	}
+
+	jar, err := loadCookieJar(path)
+	if err != nil {
+		t.Fatalf("loadCookieJar() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		URL  string
+		want string
+	}{
+		{name: "Subdomain cookie", URL: "https://sub.kdlp.underground.software/", want: "[session=abc]"},
+		{name: "Host-only cookie", URL: "http://dash.kdlp.underground.software/", want: "[token=xyz]"},
+		{name: "Secure cookie not sent over http", URL: "http://kdlp.underground.software/", want: "[]"},
+		{name: "Other host", URL: "https://github.com/", want: "[]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, _ := url.Parse(tt.URL)
+			if got := fmt.Sprint(jar.Cookies(u)); got != tt.want {
+				t.Errorf("Cookies(%q) = %s, want %s", tt.URL, got, tt.want)
+			}
+		})
+	}
+}
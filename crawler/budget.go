@@ -0,0 +1,160 @@
+package crawler
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+)
+
+// BudgetConfig holds the limits that keep a crawl from running forever, 0 means unlimited
+type BudgetConfig struct {
+
+	// Maximum number of links followed from the seed URL
+	MaxDepth int `json:"max_depth"`
+
+	// Maximum number of URLs fetched in total
+	MaxPages int `json:"max_pages"`
+
+	// Maximum number of URLs fetched from a single host
+	MaxPagesPerHost int `json:"max_pages_per_host"`
+
+	// Maximum wall-clock time of the crawl
+	MaxDuration Duration `json:"max_duration"`
+
+	// Maximum number of body bytes downloaded in total
+	MaxBytes int64 `json:"max_bytes"`
+}
+
+// Tracks how much of its budget a crawl has used up
+type crawlBudget struct {
+	config BudgetConfig
+	start  time.Time
+
+	// Protects everything below, the colly engine uses the budget from several goroutines
+	mu sync.Mutex
+
+	pages     int
+	hostPages map[string]int
+	bytes     int64
+
+	// Limit that ended the crawl, empty while it is still running
+	stopReason string
+
+	// URLs skipped because of the depth and per-host limits, which do not end the crawl, by limit.
+	// A skipped URL is reached again through every link to it, so it is kept in a set to count it once.
+	skipped map[string]map[string]bool
+}
+
+// Function to start tracking a crawl's budget
+func newCrawlBudget(config BudgetConfig) *crawlBudget {
+	return &crawlBudget{
+		config:    config,
+		start:     time.Now(),
+		hostPages: make(map[string]int),
+		skipped:   make(map[string]map[string]bool),
+	}
+}
+
+// Checks whether a URL found depth links away from the seed may still be fetched, and counts it if so.
+// The URL is in its canonical form, so every spelling of a skipped page counts as one.
+func (b *crawlBudget) allow(URL string, depth int) bool {
+	if b == nil {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.stopReason != "" {
+		return false
+	}
+
+	// Limits that end the whole crawl
+	if limit := b.config.MaxDuration.Duration; limit > 0 && time.Since(b.start) >= limit {
+		b.stopReason = fmt.Sprintf("max duration (%v) reached", limit)
+		return false
+	}
+	if limit := b.config.MaxPages; limit > 0 && b.pages >= limit {
+		b.stopReason = fmt.Sprintf("max pages (%d) reached", limit)
+		return false
+	}
+	if limit := b.config.MaxBytes; limit > 0 && b.bytes >= limit {
+		b.stopReason = fmt.Sprintf("max bytes (%d) reached", limit)
+		return false
+	}
+
+	// Limits that only skip this URL
+	if limit := b.config.MaxDepth; limit > 0 && depth > limit {
+		b.skip(fmt.Sprintf("max depth (%d)", limit), URL)
+		return false
+	}
+
+	host := ""
+	if parsedURL, err := url.Parse(URL); err == nil {
+		host = parsedURL.Host
+	}
+	if limit := b.config.MaxPagesPerHost; limit > 0 && b.hostPages[host] >= limit {
+		b.skip(fmt.Sprintf("max pages per host (%d) on %s", limit, host), URL)
+		return false
+	}
+
+	b.pages++
+	b.hostPages[host]++
+
+	return true
+}
+
+// Records a URL skipped because of a limit, the caller holds the lock
+func (b *crawlBudget) skip(limit, URL string) {
+	if b.skipped[limit] == nil {
+		b.skipped[limit] = make(map[string]bool)
+	}
+	b.skipped[limit][URL] = true
+}
+
+// Adds to the number of bytes downloaded
+func (b *crawlBudget) addBytes(n int64) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	b.bytes += n
+	b.mu.Unlock()
+}
+
+// Checks whether a limit has ended the crawl
+func (b *crawlBudget) stopped() bool {
+	if b == nil {
+		return false
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.stopReason != ""
+}
+
+// Function to describe which limits ended or trimmed the crawl, one line each
+func (b *crawlBudget) summary() []string {
+	if b == nil {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var lines []string
+	for limit, URLs := range b.skipped {
+		lines = append(lines, fmt.Sprintf("Skipped %d URLs: %s", len(URLs), limit))
+	}
+	sort.Strings(lines)
+
+	if b.stopReason != "" {
+		lines = append([]string{"Crawl stopped early: " + b.stopReason}, lines...)
+	}
+
+	return lines
+}
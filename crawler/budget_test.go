@@ -0,0 +1,99 @@
+package crawler
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_crawlBudget_allow(t *testing.T) {
+	type request struct {
+		URL   string
+		depth int
+	}
+	tests := []struct {
+		name        string
+		config      BudgetConfig
+		bytes       int64
+		requests    []request
+		want        []bool
+		wantSummary []string
+	}{
+		{
+			name:        "Unlimited",
+			requests:    []request{{"https://a.test/1", 0}, {"https://a.test/2", 50}},
+			want:        []bool{true, true},
+			wantSummary: nil,
+		},
+		{
+			name:        "Max depth skips deep URLs only",
+			config:      BudgetConfig{MaxDepth: 1},
+			requests:    []request{{"https://a.test/1", 2}, {"https://a.test/2", 1}},
+			want:        []bool{false, true},
+			wantSummary: []string{"Skipped 1 URLs: max depth (1)"},
+		},
+		{
+			name:        "URL skipped again for its depth counts once",
+			config:      BudgetConfig{MaxDepth: 1},
+			requests:    []request{{"https://a.test/1", 2}, {"https://a.test/1", 3}, {"https://a.test/2", 2}},
+			want:        []bool{false, false, false},
+			wantSummary: []string{"Skipped 2 URLs: max depth (1)"},
+		},
+		{
+			name:        "Max pages ends the crawl",
+			config:      BudgetConfig{MaxPages: 2},
+			requests:    []request{{"https://a.test/1", 0}, {"https://b.test/2", 1}, {"https://c.test/3", 1}},
+			want:        []bool{true, true, false},
+			wantSummary: []string{"Crawl stopped early: max pages (2) reached"},
+		},
+		{
+			name:        "Max pages per host skips that host only",
+			config:      BudgetConfig{MaxPagesPerHost: 1},
+			requests:    []request{{"https://a.test/1", 0}, {"https://a.test/2", 1}, {"https://b.test/3", 1}},
+			want:        []bool{true, false, true},
+			wantSummary: []string{"Skipped 1 URLs: max pages per host (1) on a.test"},
+		},
+		{
+			name:        "URL skipped again on a full host counts once",
+			config:      BudgetConfig{MaxPagesPerHost: 1},
+			requests:    []request{{"https://a.test/1", 0}, {"https://a.test/2", 1}, {"https://a.test/2", 2}},
+			want:        []bool{true, false, false},
+			wantSummary: []string{"Skipped 1 URLs: max pages per host (1) on a.test"},
+		},
+		{
+			name:        "Max bytes ends the crawl",
+			config:      BudgetConfig{MaxBytes: 100},
+			bytes:       150,
+			requests:    []request{{"https://a.test/1", 0}},
+			want:        []bool{false},
+			wantSummary: []string{"Crawl stopped early: max bytes (100) reached"},
+		},
+		{
+			name:        "Max duration ends the crawl",
+			config:      BudgetConfig{MaxDuration: Duration{time.Nanosecond}},
+			requests:    []request{{"https://a.test/1", 0}},
+			want:        []bool{false},
+			wantSummary: []string{"Crawl stopped early: max duration (1ns) reached"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newCrawlBudget(tt.config)
+			b.addBytes(tt.bytes)
+			time.Sleep(time.Millisecond)
+
+			var got []bool
+			for _, r := range tt.requests {
+				got = append(got, b.allow(r.URL, r.depth))
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("allow() = %v, want %v", got, tt.want)
+			}
+			if summary := b.summary(); !reflect.DeepEqual(summary, tt.wantSummary) {
+				t.Errorf("summary() = %q, want %q", summary, tt.wantSummary)
+			}
+		})
+	}
+}
@@ -0,0 +1,293 @@
+/*
+ * This code includes portions of Colly (https://github.com/gocolly/colly)
+ * Copyright (c) 2017 Adam Tauber (asciimoo) <https://github.com/asciimoo>
+ * Licensed under the Apache License, Version 2.0 (http://www.apache.org/licenses/LICENSE-2.0)
+ */
+
+package crawler
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gocolly/colly/v2"
+)
+
+// Keys of what colly requests carry in their context
+const (
+	// URL requested, colly replaces the request's URL when it follows a redirect
+	collyURLKey = "url"
+
+	// Page the URL was found on, or the sitemap that listed it
+	collyReferrerKey = "referrer"
+
+	// Number of links followed from the home page to get to the URL
+	collyDepthKey = "depth"
+
+	// Set once the response turned out to have no links worth following: it is not a success or it is an error page served with a 2xx status
+	collySkipLinksKey = "skip_links"
+)
+
+// Function to create the context of a request for a URL found on referringURL
+func newCollyContext(referringURL string, depth int) *colly.Context {
+	ctx := colly.NewContext()
+	ctx.Put(collyReferrerKey, referringURL)
+	ctx.Put(collyDepthKey, depth)
+	return ctx
+}
+
+// Returns the URL a colly request was made for, before any redirect
+func requestedURL(r *colly.Request) string {
+	if URL := r.Ctx.Get(collyURLKey); URL != "" {
+		return URL
+	}
+	return r.URL.String()
+}
+
+// Returns how many links away from the home page a colly request is, the home page itself has no depth in its context
+func requestDepth(r *colly.Request) int {
+	depth, _ := r.Ctx.GetAny(collyDepthKey).(int)
+	return depth
+}
+
+// Crawl crawls the site described by opts with colly, several requests at a time within the
+// configured rate limits. When ctx is done, no new requests are started and Crawl returns what it
+// found so far along with ctx's error. Each request carries the page it was found on in its
+// context, so dead links are reported with their referring URL just like the custom engine does.
+func (CollyEngine) Crawl(ctx context.Context, opts Options) (*Result, error) {
+
+	// Start the timer
+	startTime := time.Now()
+
+	cfg := opts.Config
+	if cfg == nil {
+		cfg = DefaultConfig()
+	}
+
+	// Colly's own revisit check compares URLs as written, the crawler below compares their canonical form.
+	// Colly only fetches internal pages, every other URL has its status checked by the crawler.
+	// Responses with an error status go to OnResponse like any other, with the time they took.
+	c := colly.NewCollector(
+		colly.AllowURLRevisit(),
+		colly.Async(true),
+		colly.ParseHTTPErrorResponse(),
+	)
+	c.TraceHTTP = true
+
+	// Use the same HTTP settings as the custom engine
+	transport, err := newHTTPTransport(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP transport: %w", err)
+	}
+	c.WithTransport(transport)
+	c.SetRequestTimeout(cfg.HTTP.Timeout.Duration)
+	c.UserAgent = cfg.HTTP.UserAgent
+	c.MaxBodySize = int(cfg.MaxPageSize)
+
+	if cfg.CookieFile != "" {
+		jar, err := loadCookieJar(cfg.CookieFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load cookie file: %w", err)
+		}
+		c.SetCookieJar(jar)
+	}
+
+	// Apply the per-host rate limits from the config
+	if err := c.Limits(cfg.collyLimitRules()); err != nil {
+		log.Println("Error setting rate limits:", err)
+	}
+
+	// The crawler keeps the visited URLs, link graph and dead links the same way as the custom engine,
+	// and fetches robots.txt, soft-404 probes and the sitemap with the same HTTP settings as colly
+	cr, err := newCrawler(opts.Domain, opts.HomeURL, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create crawler: %w", err)
+	}
+	cr.observers = newObserverList(opts.Observers)
+	cr.progress = opts.Progress
+
+	// Colly calls back from several goroutines, so the crawler's state is guarded
+	var mu sync.Mutex
+
+	c.OnRequest(func(r *colly.Request) {
+		if ctx.Err() != nil {
+			r.Abort()
+			return
+		}
+
+		URL := r.URL.String()
+		r.Ctx.Put(collyURLKey, URL)
+
+		// Visit each URL once, even when it is linked under different spellings
+		canonical := cfg.canonicalURL(URL)
+		mu.Lock()
+		visited := cr.visited[canonical]
+		cr.visited[canonical] = true
+		cr.graph.addURL(canonical, URL)
+		mu.Unlock()
+		if visited {
+			r.Abort()
+			return
+		}
+
+		// Skip invalid, fake and excluded URLs and those robots.txt does not allow
+		if reason := cr.filter.skip(ctx, URL); reason != "" {
+			cr.logProgress(reason+":", URL)
+			r.Abort()
+			return
+		}
+
+		// Stay within the crawl budget, a URL skipped for its depth may still be reached on a shorter path
+		if !cr.budget.allow(canonical, requestDepth(r)) {
+			mu.Lock()
+			delete(cr.visited, canonical)
+			mu.Unlock()
+			r.Abort()
+			return
+		}
+
+		// Like the custom engine, only fetch internal pages, external links and internal files such as
+		// PDFs just have their status checked, within the host's rate limit and without reading them
+		if !isInternalURL(URL, opts.Domain) || !isPageURL(URL) {
+			r.Abort()
+			check := cr.fetchStatus(ctx, URL, r.Ctx.Get(collyReferrerKey))
+
+			mu.Lock()
+			cr.recordLinkStatus(check)
+			mu.Unlock()
+			return
+		}
+
+		cr.logProgress("Visiting", r.URL)
+	})
+
+	c.OnError(func(r *colly.Response, err error) {
+		// Responses were recorded by OnResponse, errors that come with one are about parsing it
+		if r == nil || r.StatusCode != 0 {
+			return
+		}
+
+		mu.Lock()
+		cr.recordLinkStatus(LinkCheck{URL: requestedURL(r.Request), ReferringURL: r.Ctx.Get(collyReferrerKey), Err: err})
+		mu.Unlock()
+	})
+
+	// Records a link found on a page in the link graph, passes it on to the observers, then visits it
+	follow := func(page *colly.Request, href string) {
+		link := page.AbsoluteURL(href)
+		pageURL, depth := requestedURL(page), requestDepth(page)
+		if link == "" || page.Ctx.GetAny(collySkipLinksKey) != nil {
+			return
+		}
+
+		mu.Lock()
+		to := cfg.canonicalURL(link)
+		cr.graph.addURL(to, link)
+		cr.graph.addLink(cfg.canonicalURL(pageURL), to)
+		mu.Unlock()
+		cr.observers.linkDiscovered(Link{URL: link, ReferringURL: pageURL, Depth: depth + 1})
+
+		// Requests started from a page would share its context, so each link gets a context of its own
+		c.Request("GET", link, nil, newCollyContext(pageURL, depth+1), nil)
+	}
+
+	c.OnResponse(func(r *colly.Response) {
+		URL, referringURL := requestedURL(r.Request), r.Ctx.Get(collyReferrerKey)
+		cr.budget.addBytes(int64(len(r.Body)))
+		cr.logProgress("Visited", URL)
+
+		check := LinkCheck{URL: URL, ReferringURL: referringURL, StatusCode: r.StatusCode}
+		if r.Trace != nil {
+			check.Duration = r.Trace.FirstByteDuration
+		}
+
+		// Like the custom engine, only look for links on successful responses
+		if r.StatusCode < 200 || r.StatusCode >= 300 {
+			r.Ctx.Put(collySkipLinksKey, true)
+			mu.Lock()
+			cr.recordLinkStatus(check)
+			mu.Unlock()
+			return
+		}
+
+		mu.Lock()
+		cr.recordStatus(check)
+		mu.Unlock()
+
+		// Error pages served with a 2xx status are dead links too, and their links are not the page's
+		if cr.soft404.isSoft404(ctx, URL, r.Body) {
+			log.Println("Soft 404:", URL, "Status Code:", r.StatusCode)
+			mu.Lock()
+			cr.graph.setStatus(cfg.canonicalURL(URL), 404)
+			cr.addDeadLink(FindingSoft404, referringURL, URL, r.StatusCode)
+			mu.Unlock()
+
+			// Keep OnHTML from following the links of the error page
+			r.Ctx.Put(collySkipLinksKey, true)
+			return
+		}
+		cr.observers.pageFetched(Page{URL: URL, StatusCode: r.StatusCode, ContentType: r.Headers.Get("Content-Type"), Size: int64(len(r.Body))})
+
+		// OnHTML below takes care of HTML, other content with links goes through the same extractors as the custom engine
+		contentType := r.Headers.Get("Content-Type")
+		if strings.Contains(strings.ToLower(contentType), "html") {
+			return
+		}
+		if extractor := extractorFor(contentType, URL); extractor != nil {
+			for _, link := range extractor(bytes.NewReader(r.Body), URL) {
+				follow(r.Request, link)
+			}
+		}
+	})
+
+	c.OnHTML("a[href]", func(e *colly.HTMLElement) {
+		follow(e.Request, e.Attr("href"))
+	})
+
+	// Check linked stylesheets and the URLs in <style> blocks and style attributes as well
+	c.OnHTML("link[href]", func(e *colly.HTMLElement) {
+		if isStylesheetRel(e.Attr("rel")) {
+			follow(e.Request, e.Attr("href"))
+		}
+	})
+
+	c.OnHTML("style, [style]", func(e *colly.HTMLElement) {
+		css := e.Attr("style")
+		if e.Name == "style" {
+			css = e.Text
+		}
+		// AbsoluteURL("") is the page's <base href>, or the page itself if it has none
+		for _, link := range appendCSSLinks(nil, e.Request.AbsoluteURL(""), css) {
+			follow(e.Request, link)
+		}
+	})
+
+	// Seed the crawl with the pages listed in the sitemap
+	if cfg.Sitemap != "" {
+		if err := cr.loadSitemap(ctx, cfg.Sitemap); err != nil {
+			log.Println("Error reading sitemap:", err)
+		}
+	}
+
+	cr.logProgress("Starting crawl at:", opts.HomeURL)
+
+	if err := c.Visit(opts.HomeURL); err != nil {
+		return nil, fmt.Errorf("failed to start crawl: %w", err)
+	}
+
+	// Sitemap pages are starting points too, found by the sitemap rather than by a link
+	for _, page := range sortedKeys(cr.sitemapPages) {
+		c.Request("GET", page, nil, newCollyContext(cr.sitemapPages[page], 0), nil)
+	}
+
+	c.Wait()
+
+	result := cr.result()
+	result.Elapsed = time.Since(startTime)
+	return result, ctx.Err()
+}
@@ -0,0 +1,252 @@
+package crawler
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/gobwas/glob"
+)
+
+// Config holds the crawler settings that can be loaded from a JSON file
+type Config struct {
+
+	// Per-host settings, the first rule whose glob matches a host applies to it
+	Hosts []HostRule `json:"hosts"`
+
+	// Settings for the HTTP client used by both engines
+	HTTP HTTPConfig `json:"http"`
+
+	// Netscape format cookie file, its cookies are only sent to the hosts they belong to
+	CookieFile string `json:"cookie_file"`
+
+	// How URLs are normalized to decide whether they were already visited
+	Normalize NormalizeConfig `json:"normalize"`
+
+	// Limits on how far and how long a crawl may go
+	Budget BudgetConfig `json:"budget"`
+
+	// Order in which the custom engine crawls URLs: "bfs", "dfs" or "priority"
+	Order string `json:"order"`
+
+	// Maximum number of bytes read from a single page, anything after that is not scanned for links
+	MaxPageSize int64 `json:"max_page_size"`
+
+	// Sitemap to seed the crawl from and check against, a URL or a path on the crawled site
+	Sitemap string `json:"sitemap"`
+
+	// Pages the site is expected to have, a source directory or a file listing one URL or path per line
+	KnownPages string `json:"known_pages"`
+
+	// Detection of error pages served with a 2xx status
+	Soft404 Soft404Config `json:"soft_404"`
+
+	// URLs neither engine checks, as glob patterns such as "https://example.com/private/*"
+	Exclude []string `json:"exclude"`
+
+	// Compiled form of Exclude
+	excludeGlobs []glob.Glob
+}
+
+// HTTPConfig holds the HTTP client settings
+type HTTPConfig struct {
+
+	// Maximum time to establish a connection
+	ConnectTimeout Duration `json:"connect_timeout"`
+
+	// Maximum time to wait for the response headers or for the next chunk of the body
+	ReadTimeout Duration `json:"read_timeout"`
+
+	// Maximum time for a whole request, including reading the body
+	Timeout Duration `json:"timeout"`
+
+	// User-Agent sent with every request
+	UserAgent string `json:"user_agent"`
+
+	// Extra headers sent with every request
+	Headers map[string]string `json:"headers"`
+
+	// Proxy URL, HTTP_PROXY and HTTPS_PROXY are used if empty
+	Proxy string `json:"proxy"`
+
+	// PEM files with CA certificates to trust in addition to the system ones
+	CABundles []string `json:"ca_bundles"`
+}
+
+// HostRule holds the settings for every host matching DomainGlob
+type HostRule struct {
+
+	// Glob pattern matched against the host (and port) of a URL, e.g. "*.kernel.org"
+	DomainGlob string `json:"domain_glob"`
+
+	// Maximum number of concurrent requests to a matching host, 0 means unlimited
+	Parallelism int `json:"parallelism"`
+
+	// Minimum time between the start of two requests to a matching host
+	Delay Duration `json:"delay"`
+
+	// Skip robots.txt for a matching host, meant for hosts we own
+	IgnoreRobots bool `json:"ignore_robots"`
+
+	// Check links on a matching host with GET because it answers HEAD requests wrongly
+	NoHead bool `json:"no_head"`
+
+	// Do not verify the TLS certificate of a matching host, only meant for test hosts
+	InsecureSkipVerify bool `json:"insecure_skip_verify"`
+
+	// Credentials sent to a matching host, and never to any other
+	Auth *AuthConfig `json:"auth"`
+
+	// Paths on a matching host are case-insensitive, so "PAGE.html" is the same page as "page.html"
+	CaseInsensitivePaths bool `json:"case_insensitive_paths"`
+
+	// Compiled form of DomainGlob
+	glob glob.Glob
+}
+
+// Duration is a time.Duration that is written as a string such as "1s" in the config file
+type Duration struct {
+	time.Duration
+}
+
+// Parses a duration string such as "500ms" from the config file
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+
+	d.Duration = parsed
+	return nil
+}
+
+// Writes a duration back out as a string
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// Pages are scanned up to 10 MiB unless the config says otherwise
+const defaultMaxPageSize = 10 << 20
+
+// Function to create the configuration used when no config file is given
+func DefaultConfig() *Config {
+	cfg := &Config{
+		Hosts: []HostRule{
+			// Our own hosts can take a few requests at once
+			{DomainGlob: "*underground.software", Parallelism: 2, IgnoreRobots: true},
+
+			// Be polite to everyone else (kernel.org, lore, GitHub, ...)
+			{DomainGlob: "*", Parallelism: 1, Delay: Duration{time.Second}},
+		},
+		HTTP: HTTPConfig{
+			ConnectTimeout: Duration{10 * time.Second},
+			ReadTimeout:    Duration{30 * time.Second},
+			Timeout:        Duration{time.Minute},
+			UserAgent:      robotsUserAgent + "/1.0 (+https://github.com/underground-software/KDLP_Webcrawler)",
+		},
+		Normalize: NormalizeConfig{
+			IgnoreTrailingSlash: true,
+		},
+		Order:       "bfs",
+		MaxPageSize: defaultMaxPageSize,
+	}
+
+	if err := cfg.compile(); err != nil {
+		// The defaults are fixed, so this can only be a programming error
+		panic(err)
+	}
+
+	return cfg
+}
+
+// Function to load the configuration from a JSON file
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Start from the defaults so the file only needs to list what it changes
+	cfg := DefaultConfig()
+
+	// Host rules from the file replace the default ones entirely
+	defaultHosts := cfg.Hosts
+	cfg.Hosts = nil
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	if cfg.Hosts == nil {
+		cfg.Hosts = defaultHosts
+	}
+
+	if err := cfg.compile(); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+
+	return cfg, nil
+}
+
+// Compiles the globs so they can be matched quickly, and reads credentials from the environment
+func (cfg *Config) compile() error {
+	for i := range cfg.Hosts {
+		g, err := glob.Compile(cfg.Hosts[i].DomainGlob)
+		if err != nil {
+			return fmt.Errorf("bad domain_glob %q: %w", cfg.Hosts[i].DomainGlob, err)
+		}
+		cfg.Hosts[i].glob = g
+
+		if auth := cfg.Hosts[i].Auth; auth != nil {
+			if err := auth.resolve(); err != nil {
+				return fmt.Errorf("bad auth for %q: %w", cfg.Hosts[i].DomainGlob, err)
+			}
+		}
+	}
+
+	if err := cfg.Normalize.compile(); err != nil {
+		return fmt.Errorf("bad drop_params pattern: %w", err)
+	}
+
+	if err := cfg.Soft404.compile(); err != nil {
+		return fmt.Errorf("bad soft_404 pattern: %w", err)
+	}
+
+	cfg.excludeGlobs = nil
+	for _, pattern := range cfg.Exclude {
+		g, err := glob.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("bad exclude pattern %q: %w", pattern, err)
+		}
+		cfg.excludeGlobs = append(cfg.excludeGlobs, g)
+	}
+
+	return nil
+}
+
+// Returns the first host rule matching host, or nil if none does
+func (cfg *Config) hostRule(host string) *HostRule {
+	if cfg == nil {
+		return nil
+	}
+
+	for i := range cfg.Hosts {
+		if cfg.Hosts[i].glob != nil && cfg.Hosts[i].glob.Match(host) {
+			return &cfg.Hosts[i]
+		}
+	}
+
+	return nil
+}
+
+// Returns the maximum number of bytes read from a single page
+func (cfg *Config) maxPageSize() int64 {
+	if cfg == nil {
+		return defaultMaxPageSize
+	}
+	return cfg.MaxPageSize
+}
@@ -0,0 +1,93 @@
+package crawler
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func Test_loadConfig(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		wantHosts int
+		wantErr   bool
+	}{
+		{
+			name: "Host rules replace the defaults",
+			content: `{"hosts": [
+				{"domain_glob": "*.kernel.org", "parallelism": 1, "delay": "2s"}
+			]}`,
+			wantHosts: 1,
+		},
+		{
+			name:      "Missing host rules keep the defaults",
+			content:   `{}`,
+			wantHosts: len(DefaultConfig().Hosts),
+		},
+		{
+			name:    "Bad duration",
+			content: `{"hosts": [{"domain_glob": "*", "delay": "soon"}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "Bad glob",
+			content: `{"hosts": [{"domain_glob": "[kernel.org"}]}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.json")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("Error writing config file: %v", err)
+			}
+
+			got, err := LoadConfig(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(got.Hosts) != tt.wantHosts {
+				t.Errorf("LoadConfig() got %d host rules, want %d", len(got.Hosts), tt.wantHosts)
+			}
+		})
+	}
+}
+
+func TestConfig_hostRule(t *testing.T) {
+	cfg := &Config{
+		Hosts: []HostRule{
+			{DomainGlob: "*.kernel.org", Delay: Duration{2 * time.Second}},
+			{DomainGlob: "*", Delay: Duration{time.Second}},
+		},
+	}
+	if err := cfg.compile(); err != nil {
+		t.Fatalf("compile() error = %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		host      string
+		wantDelay time.Duration
+	}{
+		{name: "Specific rule", host: "lore.kernel.org", wantDelay: 2 * time.Second},
+		{name: "Catch-all rule", host: "github.com", wantDelay: time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := cfg.hostRule(tt.host)
+			if got == nil {
+				t.Fatalf("hostRule(%q) = nil, want a rule", tt.host)
+			}
+			if got.Delay.Duration != tt.wantDelay {
+				t.Errorf("hostRule(%q) delay = %v, want %v", tt.host, got.Delay, tt.wantDelay)
+			}
+		})
+	}
+}
@@ -0,0 +1,232 @@
+package crawler
+
+import (
+	"bufio"
+	"io"
+	"log"
+	"mime"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Function that pulls the links out of a response body, resolving them against baseURL
+type linkExtractor func(r io.Reader, baseURL string) []string
+
+// Link extractors by media type
+var extractorsByType = map[string]linkExtractor{
+	"text/html":             extractLinks,
+	"application/xhtml+xml": extractLinks,
+	"text/markdown":         extractMarkdownLinks,
+	"text/x-markdown":       extractMarkdownLinks,
+	"text/css":              extractCSSLinks,
+	"application/xml":       extractSitemapLinks,
+	"text/xml":              extractSitemapLinks,
+	"text/plain":            extractTextLinks,
+}
+
+// Link extractors by file extension, for responses whose Content-Type is missing or too generic to go by
+var extractorsByExtension = map[string]linkExtractor{
+	".html":     extractLinks,
+	".htm":      extractLinks,
+	".xhtml":    extractLinks,
+	".php":      extractLinks,
+	".md":       extractMarkdownLinks,
+	".markdown": extractMarkdownLinks,
+	".css":      extractCSSLinks,
+	".xml":      extractSitemapLinks,
+	".txt":      extractTextLinks,
+}
+
+// Function to pick the link extractor for a response, nil means it is a leaf resource without links
+func extractorFor(contentType, URL string) linkExtractor {
+	mediaType := ""
+	if contentType != "" {
+		parsed, _, err := mime.ParseMediaType(contentType)
+		if err != nil {
+			return nil
+		}
+		mediaType = parsed
+	}
+
+	// Servers often send Markdown and other text files as text/plain or application/octet-stream,
+	// so for those the extension is a better guide than the Content-Type
+	switch mediaType {
+	case "", "text/plain", "application/octet-stream":
+		if extractor := extractorsByExtension[urlExtension(URL)]; extractor != nil {
+			return extractor
+		}
+	}
+
+	// Without any Content-Type, assume HTML like browsers do
+	if mediaType == "" {
+		return extractLinks
+	}
+
+	return extractorsByType[mediaType]
+}
+
+// Returns the lowercased file extension of a URL's path
+func urlExtension(URL string) string {
+	parsedURL, err := url.Parse(URL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(path.Ext(parsedURL.Path))
+}
+
+// Function to call fn for every line of r, lines are read one at a time so the whole body is never in memory
+func scanLines(r io.Reader, fn func(line string)) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+
+	for scanner.Scan() {
+		fn(scanner.Text())
+	}
+
+	if err := scanner.Err(); err != nil {
+		log.Println("Error reading content:", err)
+	}
+}
+
+var (
+	// [text](url "title") and ![alt](url)
+	markdownInlineLink = regexp.MustCompile(`\]\(\s*<?([^)\s>]+)>?(?:\s+["'(][^)]*)?\)`)
+
+	// [label]: url "title"
+	markdownReferenceLink = regexp.MustCompile(`^\s{0,3}\[[^\]]+\]:\s*<?([^\s>]+)>?`)
+
+	// <https://example.com>
+	markdownAutolink = regexp.MustCompile(`<((?:https?|ftp)://[^>\s]+)>`)
+
+	// Links in raw HTML inside the Markdown
+	markdownHTMLLink = regexp.MustCompile(`(?i)<a\s[^>]*href\s*=\s*["']([^"']+)["']`)
+
+	// `inline code`
+	markdownCodeSpan = regexp.MustCompile("`[^`]*`")
+)
+
+// Function to extract the links from Markdown, skipping code blocks and code spans
+func extractMarkdownLinks(r io.Reader, baseURL string) []string {
+	var links []string
+	fence := ""
+
+	scanLines(r, func(line string) {
+		trimmed := strings.TrimSpace(line)
+
+		// Code blocks hold examples such as "http://your.computers.ip.addr", not real links
+		if fence != "" {
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+			return
+		}
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			fence = trimmed[:3]
+			return
+		}
+
+		line = markdownCodeSpan.ReplaceAllString(line, "")
+
+		for _, re := range []*regexp.Regexp{markdownInlineLink, markdownReferenceLink, markdownAutolink, markdownHTMLLink} {
+			for _, match := range re.FindAllStringSubmatch(line, -1) {
+				links = appendLink(links, baseURL, match[1])
+			}
+		}
+	})
+
+	return links
+}
+
+var (
+	// url(...) in CSS, with or without quotes
+	cssURL = regexp.MustCompile(`url\(\s*["']?([^"')]+?)["']?\s*\)`)
+
+	// @import "file.css", the url(...) form is already covered by cssURL
+	cssImport = regexp.MustCompile(`@import\s+["']([^"']+)["']`)
+
+	// /* comments */
+	cssComment = regexp.MustCompile(`(?s)/\*.*?\*/`)
+)
+
+// Function to append the URLs referenced by a piece of CSS to links, resolved against baseURL
+func appendCSSLinks(links []string, baseURL, css string) []string {
+	css = cssComment.ReplaceAllString(css, "")
+
+	for _, re := range []*regexp.Regexp{cssImport, cssURL} {
+		for _, match := range re.FindAllStringSubmatch(css, -1) {
+			// Inline images are not links
+			if strings.HasPrefix(match[1], "data:") {
+				continue
+			}
+			links = appendLink(links, baseURL, match[1])
+		}
+	}
+
+	return links
+}
+
+// Function to extract the links from a stylesheet, resolved against the stylesheet's own URL
+func extractCSSLinks(r io.Reader, baseURL string) []string {
+	var links []string
+
+	// Comments can span lines, so whether a line starts inside one carries over from the previous line
+	inComment := false
+
+	scanLines(r, func(line string) {
+		var code strings.Builder
+		for line != "" {
+			if inComment {
+				end := strings.Index(line, "*/")
+				if end < 0 {
+					break
+				}
+				line, inComment = line[end+2:], false
+				continue
+			}
+
+			start := strings.Index(line, "/*")
+			if start < 0 {
+				code.WriteString(line)
+				break
+			}
+			code.WriteString(line[:start])
+			line, inComment = line[start+2:], true
+		}
+
+		links = appendCSSLinks(links, baseURL, code.String())
+	})
+
+	return links
+}
+
+// Function to extract the page URLs from a sitemap or sitemap index, the <loc> elements of both
+func extractSitemapLinks(r io.Reader, baseURL string) []string {
+	_, links, err := parseSitemap(r, baseURL)
+	if err != nil {
+		log.Println("Error reading XML:", err)
+	}
+	return links
+}
+
+// Bare http and https URLs in plain text
+var textURL = regexp.MustCompile(`https?://[^\s<>"'` + "`" + `]+`)
+
+// Function to extract the URLs written out in plain text
+func extractTextLinks(r io.Reader, baseURL string) []string {
+	var links []string
+
+	scanLines(r, func(line string) {
+		for _, match := range textURL.FindAllString(line, -1) {
+			// Punctuation at the end of a URL usually belongs to the sentence around it
+			match = strings.TrimRight(match, ".,;:!?")
+			if strings.HasSuffix(match, ")") && !strings.Contains(match, "(") {
+				match = strings.TrimSuffix(match, ")")
+			}
+			links = appendLink(links, baseURL, match)
+		}
+	})
+
+	return links
+}
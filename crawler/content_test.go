@@ -0,0 +1,115 @@
+package crawler
+
+import (
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// Returns the name of a function, so extractors can be compared
+func funcName(f linkExtractor) string {
+	if f == nil {
+		return "<nil>"
+	}
+	return runtime.FuncForPC(reflect.ValueOf(f).Pointer()).Name()
+}
+
+func Test_extractorFor(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		URL         string
+		want        linkExtractor
+	}{
+		{name: "HTML", contentType: "text/html; charset=utf-8", URL: "https://kdlp.test/", want: extractLinks},
+		{name: "Missing Content-Type", contentType: "", URL: "https://kdlp.test/page", want: extractLinks},
+		{name: "Markdown", contentType: "text/markdown", URL: "https://kdlp.test/index.md", want: extractMarkdownLinks},
+		{name: "Markdown served as plain text", contentType: "text/plain", URL: "https://kdlp.test/index.md", want: extractMarkdownLinks},
+		{name: "Plain text", contentType: "text/plain", URL: "https://kdlp.test/notes.txt", want: extractTextLinks},
+		{name: "Stylesheet", contentType: "text/css", URL: "https://kdlp.test/style.css", want: extractCSSLinks},
+		{name: "Sitemap", contentType: "application/xml", URL: "https://kdlp.test/sitemap.xml", want: extractSitemapLinks},
+		{name: "PDF", contentType: "application/pdf", URL: "https://kdlp.test/slides.pdf", want: nil},
+		{name: "Tarball", contentType: "application/octet-stream", URL: "https://kdlp.test/linux.tar.gz", want: nil},
+		{name: "Patch", contentType: "text/x-diff", URL: "https://kdlp.test/fix.patch", want: nil},
+		{name: "Image", contentType: "image/png", URL: "https://kdlp.test/logo.png", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := extractorFor(tt.contentType, tt.URL); funcName(got) != funcName(tt.want) {
+				t.Errorf("extractorFor(%q, %q) = %s, want %s", tt.contentType, tt.URL, funcName(got), funcName(tt.want))
+			}
+		})
+	}
+}
+
+func Test_extractors(t *testing.T) {
+	baseURL := "https://kdlp.test/course/index.md"
+
+	tests := []struct {
+		name      string
+		extractor linkExtractor
+		content   string
+		want      []string
+	}{
+		{
+			name:      "Markdown",
+			extractor: extractMarkdownLinks,
+			content: "# Course\n" +
+				"See [the slides](slides.html \"Slides\") and ![logo](/logo.png).\n" +
+				"Mail <https://lore.kernel.org/> or read <a href=\"rules.html\">the rules</a>.\n" +
+				"[ref]: https://kernel.org/doc/\n" +
+				"Run `curl [x](http://your.computers.ip.addr/)` to test.\n" +
+				"```\n" +
+				"[not a link](http://your.computers.ip.addr/)\n" +
+				"```\n",
+			want: []string{
+				"https://kdlp.test/course/slides.html",
+				"https://kdlp.test/logo.png",
+				"https://lore.kernel.org/",
+				"https://kdlp.test/course/rules.html",
+				"https://kernel.org/doc/",
+			},
+		},
+		{
+			name:      "CSS",
+			extractor: extractCSSLinks,
+			content: "@import \"base.css\";\n@import url('print.css') print;\n" +
+				"body { background: url(\"../img/bg.png\"); }\n" +
+				".icon { background: url(data:image/png;base64,AAAA); }\n" +
+				"/* .old { background: url(old.png); }\n .older { background: url(older.png); } */\n" +
+				".logo { background: url( /logo.svg ) } /* url(gone.png) */\n",
+			want: []string{
+				"https://kdlp.test/course/base.css",
+				"https://kdlp.test/course/print.css",
+				"https://kdlp.test/img/bg.png",
+				"https://kdlp.test/logo.svg",
+			},
+		},
+		{
+			name:      "Sitemap",
+			extractor: extractSitemapLinks,
+			content: `<?xml version="1.0" encoding="UTF-8"?>
+				<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+					<url><loc>https://kdlp.test/index.html</loc><lastmod>2023-08-01</lastmod></url>
+					<url><loc> https://kdlp.test/course/ </loc></url>
+				</urlset>`,
+			want: []string{"https://kdlp.test/index.html", "https://kdlp.test/course/"},
+		},
+		{
+			name:      "Plain text",
+			extractor: extractTextLinks,
+			content:   "Docs live at https://kernel.org/doc/. Patches (see https://lore.kernel.org/list) go there.\n",
+			want:      []string{"https://kernel.org/doc/", "https://lore.kernel.org/list"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.extractor(strings.NewReader(tt.content), baseURL)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("extractor returned %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,638 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func Test_extractLinks_malformed(t *testing.T) {
+	type args struct {
+		content string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		domain  string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "Extract links from HTML with valid URLs",
+			args: args{
+				content: `
+					<!DOCTYPE html>
+					<html>
+					<body>
+						<a href="https://www.example.com/page1">Link 1</a>
+						<a href="https://www.example.com/page2">Link 2</a>
+					</body>
+					</html>
+				`,
+			},
+			domain: "https://www.example.com/",
+			want:   []string{"https://www.example.com/page1", "https://www.example.com/page2"},
+		},
+		{
+			name: "Extract links from HTML with relative URLs",
+			args: args{
+				content: `
+					<!DOCTYPE html>
+					<html>
+					<body>
+						<a href="https://www.example.com/page1">Link 1</a>
+						<a href="relative.html">Relative link</a>
+					</body>
+					</html>
+				`,
+			},
+			domain: "https://www.example.com/",
+			want:   []string{"https://www.example.com/page1", "https://www.example.com/relative.html"},
+		},
+		{
+			name: "Extract links from HTML with http scheme",
+			args: args{
+				content: `
+				<!DOCTYPE html>
+				<html>
+				<body>
+					<a href="http://www.example.com/page1">Link 1</a>
+					<a href="relative.html">Relative link</a>
+				</body>
+				</html>
+			`,
+			},
+			domain: "https://www.example.com/",
+			want:   []string{"http://www.example.com/page1", "https://www.example.com/relative.html"},
+		},
+		{
+			name: "Extract links from stylesheets, style blocks and style attributes",
+			args: args{
+				content: `
+					<!DOCTYPE html>
+					<html>
+					<head>
+						<link rel="stylesheet" href="/css/main.css">
+						<link rel="icon" href="/favicon.ico">
+						<style>
+							@import "print.css";
+							body { background: url('img/bg.png'); }
+						</style>
+					</head>
+					<body>
+						<div style="background-image: url(img/hero.jpg)">
+							<a href="page1">Link 1</a>
+						</div>
+					</body>
+					</html>
+				`,
+			},
+			domain: "https://www.example.com/",
+			want: []string{
+				"https://www.example.com/css/main.css",
+				"https://www.example.com/print.css",
+				"https://www.example.com/img/bg.png",
+				"https://www.example.com/img/hero.jpg",
+				"https://www.example.com/page1",
+			},
+		},
+		{
+			name: "Resolve relative links against <base href>",
+			args: args{
+				content: `
+					<!DOCTYPE html>
+					<html>
+					<head>
+						<base href="https://cdn.example.com/docs/">
+						<link rel="stylesheet" href="style.css">
+					</head>
+					<body>
+						<a href="page1.html">Link 1</a>
+						<a href="/page2.html">Link 2</a>
+						<a href="https://www.example.com/page3.html">Link 3</a>
+					</body>
+					</html>
+				`,
+			},
+			domain: "https://www.example.com/course/index.html",
+			want: []string{
+				"https://cdn.example.com/docs/style.css",
+				"https://cdn.example.com/docs/page1.html",
+				"https://cdn.example.com/page2.html",
+				"https://www.example.com/page3.html",
+			},
+		},
+		{
+			name: "Relative <base href> is resolved against the page URL",
+			args: args{
+				content: `
+					<html>
+					<head><base href="../"></head>
+					<body><a href="page1.html">Link 1</a></body>
+					</html>
+				`,
+			},
+			domain: "https://www.example.com/course/week1/index.html",
+			want:   []string{"https://www.example.com/course/page1.html"},
+		},
+		{
+			name: "Only the first <base href> counts",
+			args: args{
+				content: `
+					<html>
+					<head><base href="/first/"><base href="/second/"></head>
+					<body><a href="page1.html">Link 1</a></body>
+					</html>
+				`,
+			},
+			domain: "https://www.example.com/",
+			want:   []string{"https://www.example.com/first/page1.html"},
+		},
+		{
+			name: "<base> without href is ignored",
+			args: args{
+				content: `
+					<html>
+					<head><base target="_blank"></head>
+					<body><a href="page1.html">Link 1</a></body>
+					</html>
+				`,
+			},
+			domain: "https://www.example.com/course/",
+			want:   []string{"https://www.example.com/course/page1.html"},
+		},
+		{
+			name: "HTML parsing error",
+			args: args{
+				content: "<html><body>Malformed HTML",
+			},
+			domain: "https://www.example.com/",
+			want:   nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := extractLinks(strings.NewReader(tt.args.content), tt.domain)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("extractLinks() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TODO: Improve?
+func Test_newCrawler(t *testing.T) {
+	tests := []struct {
+		name    string
+		domain  string
+		homeURL string
+		want    *crawler
+	}{
+		{
+			name:    "Test case 1",
+			domain:  "https://website.test/",
+			homeURL: "https://website.test/index.html",
+			want: &crawler{
+				visited:   make(map[string]bool),
+				deadLinks: []DeadLink{},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newCrawler(tt.domain, tt.homeURL, DefaultConfig())
+			if err != nil {
+				t.Fatalf("newCrawler() error = %v", err)
+			}
+
+			// Check the domain and homeURL fields directly
+			if got.domain != tt.domain || got.homeURL != tt.homeURL {
+				t.Errorf("newCrawler() = %v, want %v", got, tt.want)
+			}
+
+			// Compare the visited and deadLinks fields using reflect.DeepEqual
+			if !reflect.DeepEqual(got.visited, tt.want.visited) || !reflect.DeepEqual(got.deadLinks, tt.want.deadLinks) {
+				t.Errorf("newCrawler() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCrawler_handleDeadLink(t *testing.T) {
+	type fields struct {
+		visited   map[string]bool
+		deadLinks []DeadLink
+	}
+	type args struct {
+		referringURL string
+		URL          string
+		statusCode   int
+	}
+
+	tests := []struct {
+		name       string
+		fields     fields
+		args       args
+		wantLinks  []string // Expected deadLinks after calling handleDeadLink
+		wantPassed Finding  // Expected finding passed to the observers
+		wantErrMsg string   // Expected error message
+	}{
+		{
+			name: "Handle Dead Link",
+			fields: fields{
+				visited:   map[string]bool{},
+				deadLinks: []DeadLink{},
+			},
+			args: args{
+				referringURL: "https://example.com",
+				URL:          "https://example.com/deadlink",
+				statusCode:   404,
+			},
+			wantLinks:  []string{"dead link https://example.com/deadlink found at: https://example.com"},
+			wantPassed: Finding{Kind: FindingDeadLink, URL: "https://example.com/deadlink", ReferringURL: "https://example.com", StatusCode: 404, Internal: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var passed []Finding
+			observer := ObserverFuncs{Finding: func(finding Finding) { passed = append(passed, finding) }}
+			c := &crawler{
+				domain:    "https://example.com/",
+				visited:   tt.fields.visited,
+				deadLinks: tt.fields.deadLinks,
+				observers: newObserverList([]Observer{observer}),
+			}
+
+			// Call handleDeadLink with the given arguments
+			c.handleDeadLink(tt.args.referringURL, tt.args.URL, tt.args.statusCode)
+
+			// Verify deadLinks slice
+			if !equalStringSlices(deadLinkStrings(c.deadLinks), tt.wantLinks) {
+				t.Errorf("handleDeadLink() unexpected deadLinks.\nGot: %v\nWant: %v", c.deadLinks, tt.wantLinks)
+			}
+
+			// Verify the dead link was passed on right away
+			if len(passed) != 1 || passed[0] != tt.wantPassed {
+				t.Errorf("handleDeadLink() unexpected callback.\nGot: %v\nWant: %v", passed, tt.wantPassed)
+			}
+
+			// Print debugging output
+			fmt.Println("Got deadLinks:", c.deadLinks)
+		})
+	}
+}
+
+// Helper function to turn dead links into the lines of dead_links.txt
+func deadLinkStrings(links []DeadLink) []string {
+	lines := make([]string, len(links))
+	for i, link := range links {
+		lines[i] = link.String()
+	}
+	return lines
+}
+
+// Helper function to compare two string slices
+func equalStringSlices(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestCrawler_crawlURL(t *testing.T) {
+	type fields struct {
+		visited   map[string]bool
+		deadLinks []DeadLink
+	}
+	type args struct {
+		URL          string
+		referenceURL string
+	}
+	tests := []struct {
+		name   string
+		fields fields
+		args   args
+	}{
+		{
+			name: "Handle Dead Link",
+			fields: fields{
+				visited:   map[string]bool{},
+				deadLinks: []DeadLink{},
+			},
+			args: args{
+				URL:          "https://example.com/deadlink",
+				referenceURL: "https://example.com",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &crawler{
+				visited:   tt.fields.visited,
+				deadLinks: tt.fields.deadLinks,
+			}
+			c.crawlURL(context.Background(), tt.args.URL, tt.args.referenceURL, 0)
+		})
+	}
+}
+
+func TestCrawler_crawlInternalURL(t *testing.T) {
+	type fields struct {
+		domain    string
+		homeURL   string
+		visited   map[string]bool
+		deadLinks []DeadLink
+	}
+	type args struct {
+		URL          string
+		referringURL string
+	}
+	tests := []struct {
+		name   string
+		fields fields
+		args   args
+	}{
+		{
+			name: "Valid internal URL with links",
+			fields: fields{
+				domain:    "https://website.I.Am.Testing/",
+				homeURL:   "https://website.I.Am.Testing/index.html",
+				visited:   make(map[string]bool),
+				deadLinks: []DeadLink{},
+			},
+			args: args{
+				URL:          "https://website.I.Am.Testing/page1",
+				referringURL: "https://website.I.Am.Testing/index.html",
+			},
+		},
+		{
+			name: "Invalid URL",
+			fields: fields{
+				domain:    "https://website.I.Am.Testing/",
+				homeURL:   "https://website.I.Am.Testing/index.html",
+				visited:   make(map[string]bool),
+				deadLinks: []DeadLink{},
+			},
+			args: args{
+				URL:          "invalid",
+				referringURL: "https://website.I.Am.Testing/index.html",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &crawler{
+				domain:    tt.fields.domain,
+				homeURL:   tt.fields.homeURL,
+				visited:   tt.fields.visited,
+				deadLinks: tt.fields.deadLinks,
+			}
+			c.crawlInternalURL(context.Background(), tt.args.URL, tt.args.referringURL, 0)
+
+		})
+	}
+}
+
+func TestCrawler_crawl_fetchesPagesOnce(t *testing.T) {
+	requests := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests[r.Method+" "+r.URL.Path]++
+		switch r.URL.Path {
+		case "/index.html":
+			fmt.Fprint(w, `<a href="page.html">Page</a> <a href="./page.html#top">Page again</a> <a href="slides.pdf">Slides</a> <a href="missing.html">Missing</a>`)
+		case "/page.html", "/slides.pdf":
+			fmt.Fprint(w, "content")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	cfg := &Config{Hosts: []HostRule{{DomainGlob: "*", IgnoreRobots: true}}}
+	if err := cfg.compile(); err != nil {
+		t.Fatalf("compile() error = %v", err)
+	}
+	c, err := newCrawler(server.URL+"/", server.URL+"/index.html", cfg)
+	if err != nil {
+		t.Fatalf("newCrawler() error = %v", err)
+	}
+	c.crawl(context.Background(), c.homeURL)
+
+	want := map[string]int{
+		"GET /index.html":   1,
+		"GET /page.html":    1,
+		"HEAD /slides.pdf":  1,
+		"GET /missing.html": 1,
+	}
+	if !reflect.DeepEqual(requests, want) {
+		t.Errorf("crawl() sent requests %v, want %v", requests, want)
+	}
+
+	wantDead := []string{"dead link " + server.URL + "/missing.html found at: " + server.URL + "/index.html"}
+	if !equalStringSlices(deadLinkStrings(c.deadLinks), wantDead) {
+		t.Errorf("crawl() deadLinks = %v, want %v", c.deadLinks, wantDead)
+	}
+}
+
+func TestCrawler_crawl_maxDepth(t *testing.T) {
+	// Every page links to the next one twice, forever
+	fetched := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetched = append(fetched, r.URL.Path)
+		fmt.Fprintf(w, `<a href="%[1]s0">Next</a> <a href="%[1]s0#top">Top of next</a>`, r.URL.Path)
+	}))
+	defer server.Close()
+
+	cfg := &Config{
+		Hosts:  []HostRule{{DomainGlob: "*", IgnoreRobots: true}},
+		Budget: BudgetConfig{MaxDepth: 2},
+	}
+	if err := cfg.compile(); err != nil {
+		t.Fatalf("compile() error = %v", err)
+	}
+	c, err := newCrawler(server.URL+"/", server.URL+"/p", cfg)
+	if err != nil {
+		t.Fatalf("newCrawler() error = %v", err)
+	}
+	c.crawl(context.Background(), c.homeURL)
+
+	want := []string{"/p", "/p0", "/p00"}
+	if !reflect.DeepEqual(fetched, want) {
+		t.Errorf("crawl() fetched %v, want %v", fetched, want)
+	}
+
+	// Both links to the page past the limit lead to the same URL, which is skipped once
+	wantSummary := []string{"Skipped 1 URLs: max depth (2)"}
+	if summary := c.budget.summary(); !reflect.DeepEqual(summary, wantSummary) {
+		t.Errorf("budget summary = %q, want %q", summary, wantSummary)
+	}
+}
+
+func TestCrawler_crawl_order(t *testing.T) {
+	// index links to a and b, a links to a1
+	pages := map[string]string{
+		"/index.html": `<a href="a.html">A</a> <a href="b.html">B</a>`,
+		"/a.html":     `<a href="a1.html">A1</a>`,
+		"/b.html":     ``,
+		"/a1.html":    ``,
+	}
+
+	tests := []struct {
+		name  string
+		order string
+		want  []string
+	}{
+		{name: "Breadth-first", order: "bfs", want: []string{"/index.html", "/a.html", "/b.html", "/a1.html"}},
+		{name: "Depth-first", order: "dfs", want: []string{"/index.html", "/b.html", "/a.html", "/a1.html"}},
+		{name: "Priority", order: "priority", want: []string{"/index.html", "/a.html", "/b.html", "/a1.html"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fetched := []string{}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fetched = append(fetched, r.URL.Path)
+				fmt.Fprint(w, pages[r.URL.Path])
+			}))
+			defer server.Close()
+
+			cfg := &Config{Hosts: []HostRule{{DomainGlob: "*", IgnoreRobots: true}}, Order: tt.order}
+			if err := cfg.compile(); err != nil {
+				t.Fatalf("compile() error = %v", err)
+			}
+			c, err := newCrawler(server.URL+"/", server.URL+"/index.html", cfg)
+			if err != nil {
+				t.Fatalf("newCrawler() error = %v", err)
+			}
+			c.crawl(context.Background(), c.homeURL)
+
+			if !reflect.DeepEqual(fetched, tt.want) {
+				t.Errorf("crawl() fetched %v, want %v", fetched, tt.want)
+			}
+		})
+	}
+}
+
+func TestCrawler_crawlInternalURL_content(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		maxPageSize int64
+		want        []string
+	}{
+		{
+			name:        "HTML page",
+			contentType: "text/html",
+			want:        []string{"/first.html", "/second.html"},
+		},
+		{
+			name:        "PDF served with an HTML extension",
+			contentType: "application/pdf",
+			want:        nil,
+		},
+		{
+			name:        "Page larger than max_page_size",
+			contentType: "text/html",
+			maxPageSize: 40,
+			want:        []string{"/first.html"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				fmt.Fprint(w, `<a href="first.html">1</a><p>padding</p><a href="second.html">2</a>`)
+			}))
+			defer server.Close()
+
+			cfg := &Config{MaxPageSize: tt.maxPageSize}
+			c, err := newCrawler(server.URL+"/", server.URL+"/index.html", cfg)
+			if err != nil {
+				t.Fatalf("newCrawler() error = %v", err)
+			}
+			c.crawlInternalURL(context.Background(), c.homeURL, "", 0)
+
+			var got []string
+			for c.frontier.len() > 0 {
+				item, _ := c.frontier.pop()
+				got = append(got, strings.TrimPrefix(item.URL, server.URL))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("crawlInternalURL() queued %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// The DOM based link extraction used before extractLinks, kept as a baseline for the benchmarks
+func findLinksDOM(n *html.Node, baseURL string) []string {
+	var links []string
+
+	if n.Type == html.ElementNode && n.Data == "a" {
+		for _, attr := range n.Attr {
+			if attr.Key == "href" {
+				links = appendLink(links, baseURL, attr.Val)
+			}
+		}
+	}
+
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		links = append(links, findLinksDOM(c, baseURL)...)
+	}
+
+	return links
+}
+
+// Builds a large page with many links and a lot of text between them
+func benchmarkPage(links int) string {
+	var b strings.Builder
+	b.WriteString("<!DOCTYPE html><html><body>")
+	for i := 0; i < links; i++ {
+		fmt.Fprintf(&b, `<p>Lorem ipsum dolor sit amet, consectetur adipiscing elit <a href="/page%d.html">link %d</a></p>`, i, i)
+	}
+	b.WriteString("</body></html>")
+	return b.String()
+}
+
+func Benchmark_findLinksDOM(b *testing.B) {
+	log.SetOutput(io.Discard)
+	page := benchmarkPage(10000)
+	b.SetBytes(int64(len(page)))
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		doc, err := html.Parse(strings.NewReader(page))
+		if err != nil {
+			b.Fatal(err)
+		}
+		findLinksDOM(doc, "https://kdlp.underground.software/")
+	}
+}
+
+func Benchmark_extractLinks(b *testing.B) {
+	log.SetOutput(io.Discard)
+	page := benchmarkPage(10000)
+	b.SetBytes(int64(len(page)))
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		extractLinks(strings.NewReader(page), "https://kdlp.underground.software/")
+	}
+}
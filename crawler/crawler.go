@@ -0,0 +1,163 @@
+// Package crawler finds dead links on a website.
+//
+// It starts from a home page, follows the links of every page under the site's domain and checks
+// the status of every link it finds, internal or external:
+//
+//	result, err := crawler.Crawl(ctx, crawler.Options{
+//		Domain:  "https://kdlp.underground.software/",
+//		HomeURL: "https://kdlp.underground.software/index.html",
+//	})
+//	for _, link := range result.DeadLinks {
+//		fmt.Println(link)
+//	}
+//
+// Crawl uses the custom engine, other engines are picked with NewEngine and all return the same Result.
+// Observers are told about every page, link and finding as the crawl goes.
+// Rate limits, robots.txt, crawl budgets and the other settings come from a Config,
+// see DefaultConfig and LoadConfig.
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Options describes a crawl
+type Options struct {
+
+	// URLs starting with Domain belong to the site, its pages are fetched and scanned for links
+	Domain string
+
+	// Page the crawl starts from
+	HomeURL string
+
+	// Crawler settings, DefaultConfig() if nil
+	Config *Config
+
+	// Told about every page, link and finding of the crawl as it goes
+	Observers []Observer
+
+	// Gets a line for every URL the crawl visits or skips, the command line prints them.
+	// Nothing is written if nil.
+	Progress *log.Logger
+}
+
+// DeadLink is a link to a URL that does not exist
+type DeadLink struct {
+	URL string `json:"url"`
+
+	// Page the link was found on, or the sitemap listing the URL
+	ReferringURL string `json:"referring_url,omitempty"`
+
+	StatusCode int `json:"status_code"`
+}
+
+// String describes the dead link the way dead_links.txt lists it
+func (d DeadLink) String() string {
+	// The home page is not found on any page
+	if d.ReferringURL == "" {
+		return "dead link " + d.URL
+	}
+	return "dead link " + d.URL + " found at: " + d.ReferringURL
+}
+
+// Result holds everything a crawl found
+type Result struct {
+
+	// Dead links in the order they were found
+	DeadLinks []DeadLink
+
+	// Number of distinct URLs checked
+	Visited int
+
+	// Limits of the crawl budget that ended or trimmed the crawl, one line each
+	BudgetSummary []string
+
+	// Comparison of the sitemap with the pages reachable by links, nil without a sitemap
+	Sitemap *SitemapReport
+
+	// Known pages that no link leads to, nil without known pages
+	Orphans []OrphanPage
+
+	// Wall-clock time of the crawl
+	Elapsed time.Duration
+}
+
+// Crawl crawls the site described by opts with the custom engine, see CustomEngine.
+func Crawl(ctx context.Context, opts Options) (*Result, error) {
+	return CustomEngine{}.Crawl(ctx, opts)
+}
+
+// Crawl crawls the site described by opts until every reachable URL is checked, the crawl budget
+// runs out or ctx is done. When ctx is done, Crawl returns what it found so far along with ctx's error.
+func (CustomEngine) Crawl(ctx context.Context, opts Options) (*Result, error) {
+	// Start the timer
+	startTime := time.Now()
+
+	cfg := opts.Config
+	if cfg == nil {
+		cfg = DefaultConfig()
+	}
+
+	// Create a new instance of the crawler
+	c, err := newCrawler(opts.Domain, opts.HomeURL, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create crawler: %w", err)
+	}
+	c.observers = newObserverList(opts.Observers)
+	c.progress = opts.Progress
+
+	// Seed the crawl with the pages listed in the sitemap
+	if cfg.Sitemap != "" {
+		if err := c.loadSitemap(ctx, cfg.Sitemap); err != nil {
+			log.Println("Error reading sitemap:", err)
+		}
+	}
+
+	// Crawl everything reachable from the home page and the sitemap
+	c.crawl(ctx, opts.HomeURL)
+
+	result := c.result()
+	result.Elapsed = time.Since(startTime)
+	return result, ctx.Err()
+}
+
+// Collects the result of a finished crawl, comparing the link graph with the sitemap and the known pages
+func (c *crawler) result() *Result {
+	result := &Result{
+		DeadLinks:     c.deadLinks,
+		Visited:       c.checked,
+		BudgetSummary: c.budget.summary(),
+	}
+
+	// Compare the sitemap with what the links lead to
+	if c.sitemapPages != nil {
+		report := c.checkSitemap()
+		result.Sitemap = &report
+
+		// Dead sitemap entries were passed on as dead links already
+		for _, URL := range report.Orphans {
+			c.observers.finding(Finding{Kind: FindingSitemapOrphan, URL: URL, ReferringURL: c.sitemapPages[URL], Internal: isInternalURL(URL, c.domain)})
+		}
+		for _, URL := range report.Missing {
+			c.observers.finding(Finding{Kind: FindingMissingFromSitemap, URL: URL, Internal: isInternalURL(URL, c.domain)})
+		}
+	}
+
+	// Find the known pages that no link leads to
+	if c.config.KnownPages != "" {
+		knownPages, err := loadKnownPages(c.config.KnownPages, c.domain)
+		if err != nil {
+			log.Println("Error loading known pages:", err)
+		} else {
+			result.Orphans = c.findOrphans(knownPages)
+			for _, orphan := range result.Orphans {
+				c.observers.finding(Finding{Kind: FindingOrphan, URL: orphan.URL, Internal: isInternalURL(orphan.URL, c.domain)})
+			}
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,97 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestCrawl(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/index.html":
+			fmt.Fprint(w, `<a href="page.html">Page</a> <a href="missing.html">Missing</a>`)
+		case "/page.html":
+			fmt.Fprint(w, "content")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	cfg := &Config{Hosts: []HostRule{{DomainGlob: "*", IgnoreRobots: true}}}
+	if err := cfg.compile(); err != nil {
+		t.Fatalf("compile() error = %v", err)
+	}
+
+	pages := map[string]int{}
+	var passed []Finding
+	observer := ObserverFuncs{
+		LinkChecked: func(check LinkCheck) { pages[check.URL] = check.StatusCode },
+		Finding:     func(finding Finding) { passed = append(passed, finding) },
+	}
+	result, err := Crawl(context.Background(), Options{
+		Domain:    server.URL + "/",
+		HomeURL:   server.URL + "/index.html",
+		Config:    cfg,
+		Observers: []Observer{observer},
+	})
+	if err != nil {
+		t.Fatalf("Crawl() error = %v", err)
+	}
+
+	wantDead := []DeadLink{{URL: server.URL + "/missing.html", ReferringURL: server.URL + "/index.html", StatusCode: 404}}
+	if !reflect.DeepEqual(result.DeadLinks, wantDead) {
+		t.Errorf("Crawl() DeadLinks = %v, want %v", result.DeadLinks, wantDead)
+	}
+	wantFindings := []Finding{{Kind: FindingDeadLink, URL: server.URL + "/missing.html", ReferringURL: server.URL + "/index.html", StatusCode: 404, Internal: true}}
+	if !reflect.DeepEqual(passed, wantFindings) {
+		t.Errorf("Crawl() passed findings %v, want %v", passed, wantFindings)
+	}
+
+	wantPages := map[string]int{
+		server.URL + "/index.html":   200,
+		server.URL + "/page.html":    200,
+		server.URL + "/missing.html": 404,
+	}
+	if !reflect.DeepEqual(pages, wantPages) {
+		t.Errorf("Crawl() passed pages %v, want %v", pages, wantPages)
+	}
+
+	if result.Visited != 3 {
+		t.Errorf("Crawl() Visited = %d, want 3", result.Visited)
+	}
+}
+
+func TestCrawl_cancelled(t *testing.T) {
+	// Every page links to the next one, forever, until the crawl is cancelled
+	requests := 0
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 3 {
+			cancel()
+		}
+		fmt.Fprintf(w, `<a href="%s0">Next</a>`, r.URL.Path)
+	}))
+	defer server.Close()
+
+	cfg := &Config{Hosts: []HostRule{{DomainGlob: "*", IgnoreRobots: true}}}
+	if err := cfg.compile(); err != nil {
+		t.Fatalf("compile() error = %v", err)
+	}
+
+	result, err := Crawl(ctx, Options{Domain: server.URL + "/", HomeURL: server.URL + "/p", Config: cfg})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Crawl() error = %v, want %v", err, context.Canceled)
+	}
+	if result == nil || result.Visited != 3 {
+		t.Errorf("Crawl() result = %+v, want 3 URLs visited", result)
+	}
+}
@@ -0,0 +1,368 @@
+package crawler
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// Function to create a new instance of the web crawler
+func newCrawler(domain, homeURL string, cfg *Config) (*crawler, error) {
+	client, err := newHTTPClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	frontier, err := newFrontier(cfg.Order)
+	if err != nil {
+		return nil, err
+	}
+
+	limiter := newHostLimiter(cfg)
+
+	return &crawler{
+		domain:    domain,
+		homeURL:   homeURL,
+		visited:   make(map[string]bool),
+		deadLinks: []DeadLink{},
+		config:    cfg,
+		client:    client,
+		limiter:   limiter,
+		filter:    newURLFilter(cfg, client, limiter),
+		soft404:   newSoft404Detector(cfg, client, limiter),
+		budget:    newCrawlBudget(cfg.Budget),
+		frontier:  frontier,
+		graph:     newLinkGraph(),
+		observers: newObserverList(nil),
+	}, nil
+}
+
+// Function to resolve a link found on a page and append it to links if it is valid
+func appendLink(links []string, baseURL, href string) []string {
+	// Resolve the URL to handle relative URLs correctly
+	absoluteURL, err := resolveURL(baseURL, href)
+	if err != nil {
+		log.Println("Error resolving URL:", err)
+		return links
+	}
+
+	// Check if the resolved URL is valid and store it in the links slice
+	if !isValidURL(absoluteURL) {
+		log.Println("Invalid URL found:", absoluteURL)
+		return links
+	}
+
+	return append(links, absoluteURL)
+}
+
+// Function to stream through HTML and collect the links in order of discovery, without building a DOM.
+// Besides anchors this covers linked stylesheets and the URLs in <style> blocks and style attributes.
+// Links are resolved against the page's <base href> if it has one, which belongs in the <head> before any link.
+func extractLinks(r io.Reader, baseURL string) []string {
+	var links []string
+
+	// Whether the tokenizer is inside a <style> element
+	inStyle := false
+
+	// Only the first <base href> of a document counts
+	foundBase := false
+
+	tokenizer := html.NewTokenizer(r)
+	for {
+		tokenType := tokenizer.Next()
+		switch tokenType {
+		case html.ErrorToken:
+			// io.EOF is the normal end of the page, anything else means the rest of it is unreadable
+			if err := tokenizer.Err(); err != io.EOF {
+				log.Println("Error reading HTML:", err)
+			}
+			return links
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := tokenizer.TagName()
+			tag := string(name)
+			inStyle = tag == "style" && tokenType == html.StartTagToken
+
+			// Collect the attributes that can hold links, any element can have a style attribute
+			var href, rel string
+			hasHref := false
+			for hasAttr {
+				var key, val []byte
+				key, val, hasAttr = tokenizer.TagAttr()
+				switch string(key) {
+				case "href":
+					href, hasHref = string(val), true
+				case "rel":
+					rel = string(val)
+				case "style":
+					links = appendCSSLinks(links, baseURL, string(val))
+				}
+			}
+
+			// Anchors and linked stylesheets carry links in their href
+			if hasHref && (tag == "a" || (tag == "link" && isStylesheetRel(rel))) {
+				links = appendLink(links, baseURL, href)
+			}
+
+			// From here on, resolve links against the base URL, which may itself be relative to the page
+			if hasHref && tag == "base" && !foundBase {
+				foundBase = true
+				if resolved, err := resolveURL(baseURL, href); err == nil {
+					baseURL = resolved
+				} else {
+					log.Println("Error resolving base URL:", err)
+				}
+			}
+
+		case html.TextToken:
+			if inStyle {
+				links = appendCSSLinks(links, baseURL, string(tokenizer.Text()))
+			}
+
+		case html.EndTagToken:
+			inStyle = false
+		}
+	}
+}
+
+// Checks whether the rel attribute of a <link> marks it as a stylesheet
+func isStylesheetRel(rel string) bool {
+	for _, value := range strings.Fields(rel) {
+		if strings.EqualFold(value, "stylesheet") {
+			return true
+		}
+	}
+	return false
+}
+
+// HandleDeadLink handles the case when the URL is a dead link.
+func (c *crawler) handleDeadLink(referringURL, deadURL string, statusCode int) {
+	c.addDeadLink(FindingDeadLink, referringURL, deadURL, statusCode)
+}
+
+// Records a dead link of the given kind and passes it on to the observers
+func (c *crawler) addDeadLink(kind FindingKind, referringURL, deadURL string, statusCode int) {
+
+	// Log the dead link with the referring URL and status code
+	log.Println("Dead Link:", deadURL, "found on:", referringURL, "Status Code:", statusCode)
+
+	// Append the dead link along with the referring URL to the deadLinks slice
+	c.deadLinks = append(c.deadLinks, DeadLink{URL: deadURL, ReferringURL: referringURL, StatusCode: statusCode})
+
+	// Pass the dead link on right away, so it is not lost if the crawl is cut short
+	c.observers.finding(Finding{Kind: kind, URL: deadURL, ReferringURL: referringURL, StatusCode: statusCode, Internal: isInternalURL(deadURL, c.domain)})
+}
+
+// Records the outcome of checking a URL and passes it on to the observers
+func (c *crawler) recordStatus(check LinkCheck) {
+	c.checked++
+	if check.Err == nil {
+		c.graph.setStatus(c.config.canonicalURL(check.URL), check.StatusCode)
+	}
+
+	c.observers.linkChecked(check)
+}
+
+// Checks whether a URL or another spelling of it was already visited
+func (c *crawler) isVisited(URL string) bool {
+	return c.visited[c.config.canonicalURL(URL)]
+}
+
+// Crawls the home page and then every URL in the frontier until it is empty or a limit ends the crawl
+func (c *crawler) crawl(ctx context.Context, homeURL string) {
+	c.enqueue(homeURL, "", 0) // Empty string for storing URLs
+
+	// Sitemap pages are starting points too, found by the sitemap rather than by a link
+	for _, page := range sortedKeys(c.sitemapPages) {
+		c.enqueue(page, c.sitemapPages[page], 0)
+	}
+
+	for crawled := 1; !c.budget.stopped() && ctx.Err() == nil; crawled++ {
+		item, ok := c.frontier.pop()
+		if !ok {
+			break
+		}
+
+		c.crawlURL(ctx, item.URL, item.referenceURL, item.depth)
+
+		// Display progress every now and then
+		if crawled%100 == 0 {
+			c.logProgress("Progress:", crawled, "URLs crawled,", c.frontier.len(), "queued")
+		}
+	}
+}
+
+// Adds a URL found depth links away from the home page to the frontier
+func (c *crawler) enqueue(URL, referenceURL string, depth int) {
+	c.frontier.push(crawlItem{
+		URL:          URL,
+		referenceURL: referenceURL,
+		depth:        depth,
+		internal:     isInternalURL(URL, c.domain),
+	})
+}
+
+// Initiates crawl process for a URL found depth links away from the home page
+func (c *crawler) crawlURL(ctx context.Context, URL, referenceURL string, depth int) {
+	// Check if the URL has already been visited, under any of its spellings
+	if c.isVisited(URL) {
+		c.logProgress("Already visited:", URL)
+		return
+	}
+
+	// Mark the URL as visited, the original URL is still the one fetched and reported
+	canonical := c.config.canonicalURL(URL)
+	c.visited[canonical] = true
+	c.graph.addURL(canonical, URL)
+	c.logProgress("Added", URL, "to visited map")
+
+	// Skip invalid, fake and excluded URLs and those robots.txt does not allow
+	if reason := c.filter.skip(ctx, URL); reason != "" {
+		c.logProgress(reason+":", URL)
+		return
+	}
+
+	// Stay within the crawl budget, a URL skipped for its depth may still be reached on a shorter path
+	if !c.budget.allow(canonical, depth) {
+		delete(c.visited, canonical)
+		return
+	}
+
+	// If internal page: Fetch it once, then use the response for both its status and its links
+	if isInternalURL(URL, c.domain) && isPageURL(URL) {
+		c.crawlInternalURL(ctx, URL, referenceURL, depth)
+		return
+	}
+
+	// Otherwise only fetch the status of the URL
+	c.recordLinkStatus(c.fetchStatus(ctx, URL, referenceURL))
+}
+
+// Checks the status of a URL found on referenceURL without reading its content, waiting for the host's rate limit first
+func (c *crawler) fetchStatus(ctx context.Context, URL, referenceURL string) LinkCheck {
+	release := c.limiter.acquire(URL)
+	defer release()
+
+	// Time the check itself, not the wait for the rate limit
+	start := time.Now()
+	statusCode, retried, err := c.checkStatus(ctx, URL)
+	check := LinkCheck{URL: URL, ReferringURL: referenceURL, StatusCode: statusCode, Err: err, Duration: time.Since(start)}
+	if retried {
+		check.Retries = 1
+	}
+	return check
+}
+
+// Records the status of a URL that is not scanned for links, reporting it if it is dead
+func (c *crawler) recordLinkStatus(check LinkCheck) {
+	c.recordStatus(check)
+	if check.Err != nil {
+		log.Println("Error checking status for URL:", check.URL, "Error:", check.Err)
+		return
+	}
+
+	if isDeadStatus(check.StatusCode) {
+		c.handleDeadLink(check.ReferringURL, check.URL, check.StatusCode)
+	}
+}
+
+// Checks the status of a URL, using GET right away for hosts that do not answer HEAD correctly.
+// Also tells whether the URL had to be asked again with GET after HEAD was refused.
+func (c *crawler) checkStatus(ctx context.Context, URL string) (int, bool, error) {
+	parsedURL, err := url.Parse(URL)
+	if err != nil {
+		return 0, false, err
+	}
+
+	if rule := c.config.hostRule(parsedURL.Host); rule != nil && rule.NoHead {
+		statusCode, err := getURLStatus(ctx, c.client, URL)
+		return statusCode, false, err
+	}
+
+	return headURLStatus(ctx, c.client, URL)
+}
+
+// fetches content, checks status, extracts URLs, and queues URLs for internal links
+func (c *crawler) crawlInternalURL(ctx context.Context, URL, referringURL string, depth int) {
+	// Fetch the page, waiting for the host's rate limit first. The slot is freed early for soft-404
+	// detection, whose probe needs a slot of the same host.
+	var releaseOnce sync.Once
+	release := c.limiter.acquire(URL)
+	defer releaseOnce.Do(release)
+
+	start := time.Now()
+	resp, err := fetchHTTPResponse(ctx, c.client, URL)
+	if err != nil {
+		c.recordStatus(LinkCheck{URL: URL, ReferringURL: referringURL, Err: err, Duration: time.Since(start)})
+		log.Println("Error fetching contents of URL:", URL, "Error:", err)
+		return
+	}
+	defer resp.Body.Close()
+	c.recordStatus(LinkCheck{URL: URL, ReferringURL: referringURL, StatusCode: resp.StatusCode, Duration: time.Since(start)})
+
+	if isDeadStatus(resp.StatusCode) {
+		c.handleDeadLink(referringURL, URL, resp.StatusCode)
+		return
+	}
+
+	// Only successful responses have links worth extracting
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return
+	}
+
+	// Pick the link extractor for the content, everything else is a leaf that only needed its status checked
+	extractor := extractorFor(resp.Header.Get("Content-Type"), URL)
+	if extractor == nil {
+		c.logProgress("No links to extract from:", URL, "Content-Type:", resp.Header.Get("Content-Type"))
+		return
+	}
+
+	// Stream the links out of the page, reading at most max_page_size bytes of it
+	body := newCappedReader(resp.Body, c.config.maxPageSize())
+
+	// Soft-404 detection needs the whole page, so keep a copy of what the extractor reads
+	var content bytes.Buffer
+	var r io.Reader = body
+	if c.soft404.enabled() {
+		r = io.TeeReader(body, &content)
+	}
+
+	links := extractor(r, URL)
+	c.budget.addBytes(body.n)
+	if body.truncated {
+		log.Println("Page larger than", c.config.maxPageSize(), "bytes, only its beginning was scanned:", URL)
+	}
+
+	// The page is read, so the host's slot is no longer needed
+	releaseOnce.Do(release)
+
+	// An error page served with a 2xx status is as dead as a 404, and its links are not the page's
+	if c.soft404.enabled() && c.soft404.isSoft404(ctx, URL, content.Bytes()) {
+		log.Println("Soft 404:", URL, "Status Code:", resp.StatusCode)
+		c.graph.setStatus(c.config.canonicalURL(URL), 404)
+		c.addDeadLink(FindingSoft404, referringURL, URL, resp.StatusCode)
+		return
+	}
+
+	c.observers.pageFetched(Page{URL: URL, StatusCode: resp.StatusCode, ContentType: resp.Header.Get("Content-Type"), Size: body.n})
+
+	// Iterate through the links, recording every one in the link graph but only queueing unvisited links
+	from := c.config.canonicalURL(URL)
+	for _, link := range links {
+		to := c.config.canonicalURL(link)
+		c.graph.addURL(to, link)
+		c.graph.addLink(from, to)
+		c.observers.linkDiscovered(Link{URL: link, ReferringURL: URL, Depth: depth + 1})
+
+		if !c.isVisited(link) {
+			c.enqueue(link, URL, depth+1)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Engine crawls a site. Engines find the same dead links and report them the same way,
+// they only differ in how fast they go and how much they keep track of.
+type Engine interface {
+	Crawl(ctx context.Context, opts Options) (*Result, error)
+}
+
+// CustomEngine crawls one URL at a time from an explicit frontier, in the order set by the config.
+type CustomEngine struct{}
+
+// CollyEngine crawls concurrently with colly, so it finds pages and dead links in no particular order.
+type CollyEngine struct{}
+
+// Engines by the name used to pick them
+var engines = map[string]Engine{
+	"custom": CustomEngine{},
+	"colly":  CollyEngine{},
+}
+
+// NewEngine returns the engine called name: "custom" or "colly"
+func NewEngine(name string) (Engine, error) {
+	if engine, ok := engines[name]; ok {
+		return engine, nil
+	}
+
+	names := make([]string, 0, len(engines))
+	for name := range engines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return nil, fmt.Errorf("unknown engine %q, expected one of: %s", name, strings.Join(names, ", "))
+}
@@ -0,0 +1,304 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+)
+
+func TestNewEngine(t *testing.T) {
+	tests := []struct {
+		name    string
+		engine  string
+		want    Engine
+		wantErr bool
+	}{
+		{
+			name:   "Custom engine",
+			engine: "custom",
+			want:   CustomEngine{},
+		},
+		{
+			name:   "Colly engine",
+			engine: "colly",
+			want:   CollyEngine{},
+		},
+		{
+			name:    "Unknown engine",
+			engine:  "wget",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewEngine(tt.engine)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewEngine() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("NewEngine() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEngine_Crawl(t *testing.T) {
+	server := httptest.NewTLSServer(observedSite())
+	defer server.Close()
+
+	cfg := &Config{
+		Hosts:       []HostRule{{DomainGlob: "*", IgnoreRobots: true, InsecureSkipVerify: true}},
+		MaxPageSize: defaultMaxPageSize,
+		Exclude:     []string{"*/excluded*"},
+	}
+	if err := cfg.compile(); err != nil {
+		t.Fatalf("compile() error = %v", err)
+	}
+
+	// Both engines should find the same dead links on the same site and exit the same way
+	for name, engine := range engines {
+		t.Run(name, func(t *testing.T) {
+			result, err := engine.Crawl(context.Background(), Options{
+				Domain:  server.URL + "/",
+				HomeURL: server.URL + "/",
+				Config:  cfg,
+			})
+			if err != nil {
+				t.Fatalf("Crawl() error = %v", err)
+			}
+
+			want := []DeadLink{{URL: server.URL + "/missing.html", ReferringURL: server.URL + "/", StatusCode: 404}}
+			if !reflect.DeepEqual(result.DeadLinks, want) {
+				t.Errorf("Crawl() DeadLinks = %v, want %v", result.DeadLinks, want)
+			}
+
+			if result.Visited != 3 {
+				t.Errorf("Crawl() Visited = %d, want 3", result.Visited)
+			}
+
+			if code := ExitCode(result, err); code != ExitFindings {
+				t.Errorf("ExitCode() = %d, want %d", code, ExitFindings)
+			}
+		})
+	}
+}
+
+func TestEngine_Crawl_sitemap(t *testing.T) {
+	// The home page links to linked.html twice under different spellings and to unlisted.html,
+	// which links to missing.html. The sitemap lists linked.html, orphan.html and gone.html.
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/index.html":
+			fmt.Fprint(w, `<a href="linked.html">Linked</a> <a href="./linked.html#top">Linked</a> <a href="unlisted.html">Unlisted</a>`)
+		case "/unlisted.html":
+			fmt.Fprint(w, `<a href="missing.html">Missing</a>`)
+		case "/linked.html", "/orphan.html":
+			fmt.Fprint(w, "content")
+		case "/sitemap.xml":
+			fmt.Fprint(w, `<urlset><url><loc>/index.html</loc></url><url><loc>/linked.html</loc></url><url><loc>/orphan.html</loc></url><url><loc>/gone.html</loc></url></urlset>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	cfg := &Config{Hosts: []HostRule{{DomainGlob: "*", IgnoreRobots: true}}, Sitemap: "/sitemap.xml"}
+	if err := cfg.compile(); err != nil {
+		t.Fatalf("compile() error = %v", err)
+	}
+
+	// Both engines should report the same dead links, with the page or sitemap they were found on
+	wantDead := []string{
+		"dead link " + server.URL + "/gone.html found at: " + server.URL + "/sitemap.xml",
+		"dead link " + server.URL + "/missing.html found at: " + server.URL + "/unlisted.html",
+	}
+	wantSitemap := &SitemapReport{
+		Orphans: []string{server.URL + "/orphan.html"},
+		Missing: []string{server.URL + "/unlisted.html"},
+		Dead:    []string{server.URL + "/gone.html"},
+	}
+
+	for name, engine := range engines {
+		t.Run(name, func(t *testing.T) {
+			result, err := engine.Crawl(context.Background(), Options{
+				Domain:  server.URL + "/",
+				HomeURL: server.URL + "/index.html",
+				Config:  cfg,
+			})
+			if err != nil {
+				t.Fatalf("Crawl() error = %v", err)
+			}
+
+			// Colly finds the dead links in no particular order
+			got := deadLinkStrings(result.DeadLinks)
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, wantDead) {
+				t.Errorf("Crawl() DeadLinks = %v, want %v", got, wantDead)
+			}
+
+			if !reflect.DeepEqual(result.Sitemap, wantSitemap) {
+				t.Errorf("Crawl() Sitemap = %+v, want %+v", result.Sitemap, wantSitemap)
+			}
+
+			// index.html, linked.html, unlisted.html, orphan.html, gone.html and missing.html
+			if result.Visited != 6 {
+				t.Errorf("Crawl() Visited = %d, want 6", result.Visited)
+			}
+		})
+	}
+}
+
+func TestEngine_Crawl_external(t *testing.T) {
+	// The external site records the requests it gets, answering every URL but /gone
+	var mu sync.Mutex
+	var requests []string
+	external := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		mu.Unlock()
+		if r.URL.Path == "/gone" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, `<a href="/never-crawled">Link</a>`)
+	}))
+	defer external.Close()
+
+	// Both pages of the site link to the same external URLs
+	links := fmt.Sprintf(`<a href="%[1]s/ok">OK</a> <a href="%[1]s/gone">Gone</a>`, external.URL)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/index.html":
+			fmt.Fprint(w, `<a href="page.html">Page</a> `+links)
+		case "/page.html":
+			fmt.Fprint(w, links)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	cfg := &Config{Hosts: []HostRule{{DomainGlob: "*", IgnoreRobots: true}}}
+	if err := cfg.compile(); err != nil {
+		t.Fatalf("compile() error = %v", err)
+	}
+
+	for name, engine := range engines {
+		t.Run(name, func(t *testing.T) {
+			requests = nil
+			result, err := engine.Crawl(context.Background(), Options{
+				Domain:  server.URL + "/",
+				HomeURL: server.URL + "/index.html",
+				Config:  cfg,
+			})
+			if err != nil {
+				t.Fatalf("Crawl() error = %v", err)
+			}
+
+			// Colly may find the links on either page first
+			if len(result.DeadLinks) != 1 || result.DeadLinks[0].URL != external.URL+"/gone" || result.DeadLinks[0].StatusCode != 404 {
+				t.Errorf("Crawl() DeadLinks = %v, want only %s/gone", result.DeadLinks, external.URL)
+			}
+
+			// External links are checked once each, with HEAD, and never crawled
+			sort.Strings(requests)
+			want := []string{"HEAD /gone", "HEAD /ok"}
+			if !reflect.DeepEqual(requests, want) {
+				t.Errorf("Crawl() sent the external site %v, want %v", requests, want)
+			}
+		})
+	}
+}
+
+func TestEngine_Crawl_linkChecks(t *testing.T) {
+	// The PDF is served by a server that refuses HEAD, so it is asked again with GET
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/index.html":
+			fmt.Fprint(w, `<a href="notes.pdf">Notes</a>`)
+		case r.Method == http.MethodHead:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		default:
+			fmt.Fprint(w, "%PDF")
+		}
+	}))
+	defer server.Close()
+
+	cfg := &Config{Hosts: []HostRule{{DomainGlob: "*", IgnoreRobots: true}}}
+	if err := cfg.compile(); err != nil {
+		t.Fatalf("compile() error = %v", err)
+	}
+
+	for name, engine := range engines {
+		t.Run(name, func(t *testing.T) {
+			checks := map[string]LinkCheck{}
+			observer := ObserverFuncs{LinkChecked: func(check LinkCheck) { checks[check.URL] = check }}
+			if _, err := engine.Crawl(context.Background(), Options{
+				Domain:    server.URL + "/",
+				HomeURL:   server.URL + "/index.html",
+				Config:    cfg,
+				Observers: []Observer{observer},
+			}); err != nil {
+				t.Fatalf("Crawl() error = %v", err)
+			}
+
+			for _, path := range []string{"/index.html", "/notes.pdf"} {
+				if check := checks[server.URL+path]; check.StatusCode != 200 || check.Duration <= 0 {
+					t.Errorf("Crawl() checked %s as %+v, want status 200 and the time it took", path, check)
+				}
+			}
+			if retries := checks[server.URL+"/notes.pdf"].Retries; retries != 1 {
+				t.Errorf("Crawl() retried the PDF %d times, want 1", retries)
+			}
+		})
+	}
+}
+
+func Test_urlFilter_skip(t *testing.T) {
+	cfg := &Config{Exclude: []string{"https://kdlp.underground.software/private/*"}}
+	if err := cfg.compile(); err != nil {
+		t.Fatalf("compile() error = %v", err)
+	}
+	f := &urlFilter{config: cfg}
+
+	tests := []struct {
+		name string
+		URL  string
+		want string
+	}{
+		{
+			name: "Checked URL",
+			URL:  "https://kdlp.underground.software/index.html",
+			want: "",
+		},
+		{
+			name: "Invalid URL",
+			URL:  "/index.html",
+			want: "Invalid URL",
+		},
+		{
+			name: "Fake URL",
+			URL:  "http://your.computers.ip.addr/",
+			want: "Fake URL",
+		},
+		{
+			name: "Excluded URL",
+			URL:  "https://kdlp.underground.software/private/grades.html",
+			want: "Excluded",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := f.skip(context.Background(), tt.URL); got != tt.want {
+				t.Errorf("skip() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package crawler
+
+import (
+	"context"
+	"net/http"
+)
+
+// Decides which URLs a crawl leaves alone, the same way for every engine
+type urlFilter struct {
+	config *Config
+	robots *robotsCache
+}
+
+// Function to create the URL filter of a crawl
+func newURLFilter(cfg *Config, client *http.Client, limiter *hostLimiter) *urlFilter {
+	return &urlFilter{
+		config: cfg,
+		robots: newRobotsCache(cfg, client, limiter),
+	}
+}
+
+// Returns why a URL should not be checked, or an empty string if it should be
+func (f *urlFilter) skip(ctx context.Context, URL string) string {
+	// Check if the URL is valid
+	if !isValidURL(URL) {
+		return "Invalid URL"
+	}
+
+	// Check if the URL should be treated as a fake URL and skip processing it
+	if isFakeURL(URL) {
+		return "Fake URL"
+	}
+
+	// Skip the URLs the config excludes
+	if f != nil && f.config != nil {
+		for _, g := range f.config.excludeGlobs {
+			if g.Match(URL) {
+				return "Excluded"
+			}
+		}
+	}
+
+	// Skip the URL if its host's robots.txt does not allow crawling it
+	if f != nil && !f.robots.allowed(ctx, URL) {
+		return "Disallowed by robots.txt"
+	}
+
+	return ""
+}
+
+// Function to decide whether a status code makes a link dead, the same way for every engine
+func isDeadStatus(statusCode int) bool {
+	return statusCode == http.StatusNotFound
+}
@@ -0,0 +1,142 @@
+package crawler
+
+import (
+	"container/heap"
+	"fmt"
+)
+
+// A URL waiting to be crawled
+type crawlItem struct {
+	URL          string
+	referenceURL string
+	depth        int
+
+	// Whether the URL is an internal page, used by the priority order
+	internal bool
+
+	// Order in which the item was pushed, keeps the priority order stable
+	seq int
+}
+
+// Holds the URLs waiting to be crawled and decides which one comes next
+type frontier interface {
+	push(item crawlItem)
+	pop() (crawlItem, bool)
+	len() int
+}
+
+// Function to create the frontier for a crawl order: "bfs" (the default), "dfs" or "priority"
+func newFrontier(order string) (frontier, error) {
+	switch order {
+	case "", "bfs":
+		return &queueFrontier{}, nil
+	case "dfs":
+		return &stackFrontier{}, nil
+	case "priority":
+		return &priorityFrontier{}, nil
+	}
+
+	return nil, fmt.Errorf("unknown crawl order %q, expected bfs, dfs or priority", order)
+}
+
+// Breadth-first: URLs are crawled in the order they were found, level by level
+type queueFrontier struct {
+	items []crawlItem
+}
+
+func (f *queueFrontier) push(item crawlItem) {
+	f.items = append(f.items, item)
+}
+
+func (f *queueFrontier) pop() (crawlItem, bool) {
+	if len(f.items) == 0 {
+		return crawlItem{}, false
+	}
+
+	item := f.items[0]
+	f.items = f.items[1:]
+	return item, true
+}
+
+func (f *queueFrontier) len() int {
+	return len(f.items)
+}
+
+// Depth-first: the most recently found URL is crawled next
+type stackFrontier struct {
+	items []crawlItem
+}
+
+func (f *stackFrontier) push(item crawlItem) {
+	f.items = append(f.items, item)
+}
+
+func (f *stackFrontier) pop() (crawlItem, bool) {
+	if len(f.items) == 0 {
+		return crawlItem{}, false
+	}
+
+	item := f.items[len(f.items)-1]
+	f.items = f.items[:len(f.items)-1]
+	return item, true
+}
+
+func (f *stackFrontier) len() int {
+	return len(f.items)
+}
+
+// Priority: internal pages before external links, then shallower URLs first, then in the order they were found
+type priorityFrontier struct {
+	items itemHeap
+	seq   int
+}
+
+func (f *priorityFrontier) push(item crawlItem) {
+	item.seq = f.seq
+	f.seq++
+	heap.Push(&f.items, item)
+}
+
+func (f *priorityFrontier) pop() (crawlItem, bool) {
+	if len(f.items) == 0 {
+		return crawlItem{}, false
+	}
+
+	return heap.Pop(&f.items).(crawlItem), true
+}
+
+func (f *priorityFrontier) len() int {
+	return len(f.items)
+}
+
+// Implements heap.Interface for the priority frontier
+type itemHeap []crawlItem
+
+func (h itemHeap) Len() int {
+	return len(h)
+}
+
+func (h itemHeap) Less(i, j int) bool {
+	if h[i].internal != h[j].internal {
+		return h[i].internal
+	}
+	if h[i].depth != h[j].depth {
+		return h[i].depth < h[j].depth
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h itemHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+}
+
+func (h *itemHeap) Push(x any) {
+	*h = append(*h, x.(crawlItem))
+}
+
+func (h *itemHeap) Pop() any {
+	old := *h
+	item := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return item
+}
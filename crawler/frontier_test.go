@@ -0,0 +1,72 @@
+package crawler
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_newFrontier(t *testing.T) {
+	// Pushed in this order: an external link, a deep internal page, a shallow internal page
+	items := []crawlItem{
+		{URL: "https://github.com/", depth: 1},
+		{URL: "https://kdlp.underground.software/deep.html", depth: 2, internal: true},
+		{URL: "https://kdlp.underground.software/shallow.html", depth: 1, internal: true},
+	}
+
+	tests := []struct {
+		name    string
+		order   string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:  "Breadth-first",
+			order: "bfs",
+			want:  []string{"https://github.com/", "https://kdlp.underground.software/deep.html", "https://kdlp.underground.software/shallow.html"},
+		},
+		{
+			name:  "Depth-first",
+			order: "dfs",
+			want:  []string{"https://kdlp.underground.software/shallow.html", "https://kdlp.underground.software/deep.html", "https://github.com/"},
+		},
+		{
+			name:  "Priority",
+			order: "priority",
+			want:  []string{"https://kdlp.underground.software/shallow.html", "https://kdlp.underground.software/deep.html", "https://github.com/"},
+		},
+		{
+			name:    "Unknown order",
+			order:   "random",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := newFrontier(tt.order)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newFrontier() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			for _, item := range items {
+				f.push(item)
+			}
+
+			var got []string
+			for f.len() > 0 {
+				item, _ := f.pop()
+				got = append(got, item.URL)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("pop() order = %v, want %v", got, tt.want)
+			}
+			if _, ok := f.pop(); ok {
+				t.Errorf("pop() on an empty frontier returned an item")
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package crawler
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// Adds the configured User-Agent and headers to every request
+type headerTransport struct {
+	base      http.RoundTripper
+	userAgent string
+	headers   map[string]string
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// A RoundTripper must not modify the caller's request
+	req = req.Clone(req.Context())
+
+	for name, value := range t.headers {
+		if req.Header.Get(name) == "" {
+			req.Header.Set(name, value)
+		}
+	}
+	if t.userAgent != "" {
+		req.Header.Set("User-Agent", t.userAgent)
+	}
+
+	return t.base.RoundTrip(req)
+}
+
+// Applies the host rule of each request: adds its credentials and skips certificate verification if asked to
+type hostTransport struct {
+	config   *Config
+	secure   http.RoundTripper
+	insecure http.RoundTripper
+}
+
+func (t *hostTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rule := t.config.hostRule(req.URL.Host)
+	if rule == nil {
+		return t.secure.RoundTrip(req)
+	}
+
+	// The rule is looked up for every request, so redirects to other hosts never get the credentials
+	if rule.Auth != nil {
+		req = req.Clone(req.Context())
+		rule.Auth.apply(req)
+	}
+
+	if rule.InsecureSkipVerify {
+		return t.insecure.RoundTrip(req)
+	}
+	return t.secure.RoundTrip(req)
+}
+
+// Connection that fails a read if no data arrives within timeout
+type readTimeoutConn struct {
+	net.Conn
+	timeout time.Duration
+}
+
+func (c *readTimeoutConn) Read(b []byte) (int, error) {
+	if err := c.Conn.SetReadDeadline(time.Now().Add(c.timeout)); err != nil {
+		return 0, err
+	}
+	return c.Conn.Read(b)
+}
+
+// Function to create the HTTP client used by the custom engine
+func newHTTPClient(cfg *Config) (*http.Client, error) {
+	transport, err := newHTTPTransport(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &http.Client{
+		Transport: transport,
+		Timeout:   cfg.HTTP.Timeout.Duration,
+	}
+
+	if cfg.CookieFile != "" {
+		if client.Jar, err = loadCookieJar(cfg.CookieFile); err != nil {
+			return nil, fmt.Errorf("failed to load cookie file: %w", err)
+		}
+	}
+
+	return client, nil
+}
+
+// Function to create the transport shared by both engines from the http section of the config
+func newHTTPTransport(cfg *Config) (http.RoundTripper, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	// Connect timeout, plus the read timeout applied to every read on the connection
+	dialer := &net.Dialer{Timeout: cfg.HTTP.ConnectTimeout.Duration, KeepAlive: 30 * time.Second}
+	transport.DialContext = dialer.DialContext
+	if readTimeout := cfg.HTTP.ReadTimeout.Duration; readTimeout > 0 {
+		transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			conn, err := dialer.DialContext(ctx, network, addr)
+			if err != nil {
+				return nil, err
+			}
+			return &readTimeoutConn{Conn: conn, timeout: readTimeout}, nil
+		}
+		transport.ResponseHeaderTimeout = readTimeout
+	}
+
+	// Use the configured proxy, or the usual HTTP_PROXY/HTTPS_PROXY variables if there is none
+	if cfg.HTTP.Proxy != "" {
+		proxyURL, err := url.Parse(cfg.HTTP.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("bad proxy URL: %w", err)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	tlsConfig, err := newTLSConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	transport.TLSClientConfig = tlsConfig
+
+	// Test hosts that skip certificate verification get a transport of their own
+	insecureTransport := transport.Clone()
+	insecureTransport.TLSClientConfig.InsecureSkipVerify = true
+
+	return &headerTransport{
+		base: &hostTransport{
+			config:   cfg,
+			secure:   transport,
+			insecure: insecureTransport,
+		},
+		userAgent: cfg.HTTP.UserAgent,
+		headers:   cfg.HTTP.Headers,
+	}, nil
+}
+
+// Function to build the TLS settings with the extra CA bundles
+func newTLSConfig(cfg *Config) (*tls.Config, error) {
+	tlsConfig := &tls.Config{}
+
+	// Trust the extra CAs on top of the system ones
+	if len(cfg.HTTP.CABundles) > 0 {
+		roots, err := x509.SystemCertPool()
+		if err != nil {
+			roots = x509.NewCertPool()
+		}
+
+		for _, bundle := range cfg.HTTP.CABundles {
+			pem, err := os.ReadFile(bundle)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+			}
+			if !roots.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no certificates found in CA bundle %s", bundle)
+			}
+		}
+
+		tlsConfig.RootCAs = roots
+	}
+
+	return tlsConfig, nil
+}
@@ -0,0 +1,152 @@
+package crawler
+
+import (
+	"context"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func Test_newHTTPClient_headers(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.Header.Get("User-Agent")+"|"+r.Header.Get("X-Course"))
+	}))
+	defer server.Close()
+
+	cfg := DefaultConfig()
+	cfg.HTTP.UserAgent = "KDLP_Webcrawler/test"
+	cfg.HTTP.Headers = map[string]string{"X-Course": "kdlp"}
+
+	client, err := newHTTPClient(cfg)
+	if err != nil {
+		t.Fatalf("newHTTPClient() error = %v", err)
+	}
+
+	resp, err := fetchHTTPResponse(context.Background(), client, server.URL)
+	if err != nil {
+		t.Fatalf("fetchHTTPResponse() error = %v", err)
+	}
+	got, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatalf("reading the response body: %v", err)
+	}
+	if want := "KDLP_Webcrawler/test|kdlp"; string(got) != want {
+		t.Errorf("server saw headers %q, want %q", got, want)
+	}
+}
+
+func Test_newHTTPClient_timeouts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		set     func(cfg *Config)
+		wantErr bool
+	}{
+		{
+			name:    "Slow response within timeouts",
+			set:     func(cfg *Config) {},
+			wantErr: false,
+		},
+		{
+			name:    "Read timeout",
+			set:     func(cfg *Config) { cfg.HTTP.ReadTimeout = Duration{50 * time.Millisecond} },
+			wantErr: true,
+		},
+		{
+			name:    "Overall timeout",
+			set:     func(cfg *Config) { cfg.HTTP.Timeout = Duration{50 * time.Millisecond} },
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			tt.set(cfg)
+
+			client, err := newHTTPClient(cfg)
+			if err != nil {
+				t.Fatalf("newHTTPClient() error = %v", err)
+			}
+
+			_, err = getURLStatus(context.Background(), client, server.URL)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("getURLStatus() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_newHTTPClient_tls(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	// Write the test server's self-signed certificate out as a CA bundle
+	bundle := filepath.Join(t.TempDir(), "ca.pem")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(bundle, certPEM, 0644); err != nil {
+		t.Fatalf("Error writing CA bundle: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		set     func(cfg *Config)
+		wantErr bool
+	}{
+		{
+			name:    "Untrusted certificate",
+			set:     func(cfg *Config) {},
+			wantErr: true,
+		},
+		{
+			name:    "Certificate trusted through CA bundle",
+			set:     func(cfg *Config) { cfg.HTTP.CABundles = []string{bundle} },
+			wantErr: false,
+		},
+		{
+			name: "Verification skipped for the test host",
+			set: func(cfg *Config) {
+				cfg.Hosts = []HostRule{{DomainGlob: "127.0.0.1:*", InsecureSkipVerify: true}}
+			},
+			wantErr: false,
+		},
+		{
+			name: "Verification only skipped for other hosts",
+			set: func(cfg *Config) {
+				cfg.Hosts = []HostRule{{DomainGlob: "staging.test", InsecureSkipVerify: true}}
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			tt.set(cfg)
+			if err := cfg.compile(); err != nil {
+				t.Fatalf("compile() error = %v", err)
+			}
+
+			client, err := newHTTPClient(cfg)
+			if err != nil {
+				t.Fatalf("newHTTPClient() error = %v", err)
+			}
+
+			_, err = getURLStatus(context.Background(), client, server.URL)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("getURLStatus() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package crawler
+
+// Records which pages link to which URLs, and the status of every URL checked
+type linkGraph struct {
+
+	// Links found on each page, both keyed and listed by canonical URL
+	out map[string][]string
+
+	// Status code of every URL checked by canonical URL, 0 if it could not be fetched
+	status map[string]int
+
+	// First spelling seen of each canonical URL, used in reports
+	spelling map[string]string
+}
+
+// Function to create an empty link graph
+func newLinkGraph() *linkGraph {
+	return &linkGraph{
+		out:      make(map[string][]string),
+		status:   make(map[string]int),
+		spelling: make(map[string]string),
+	}
+}
+
+// Remembers the original spelling of a canonical URL, the first one seen wins
+func (g *linkGraph) addURL(canonical, URL string) {
+	if g == nil {
+		return
+	}
+	if _, ok := g.spelling[canonical]; !ok {
+		g.spelling[canonical] = URL
+	}
+}
+
+// Records a link from one page to a URL, both canonical. Nothing is recorded without a graph.
+func (g *linkGraph) addLink(from, to string) {
+	if g == nil {
+		return
+	}
+	for _, existing := range g.out[from] {
+		if existing == to {
+			return
+		}
+	}
+	g.out[from] = append(g.out[from], to)
+}
+
+// Records the status code of a URL
+func (g *linkGraph) setStatus(canonical string, statusCode int) {
+	if g == nil {
+		return
+	}
+	g.status[canonical] = statusCode
+}
+
+// Returns the URL as it was first linked, for reports
+func (g *linkGraph) original(canonical string) string {
+	if URL, ok := g.spelling[canonical]; ok {
+		return URL
+	}
+	return canonical
+}
+
+// Function to find every URL that can be reached by following links from a page
+func (g *linkGraph) reachable(from string) map[string]bool {
+	seen := map[string]bool{from: true}
+	queue := []string{from}
+
+	for len(queue) > 0 {
+		page := queue[0]
+		queue = queue[1:]
+
+		for _, link := range g.out[page] {
+			if !seen[link] {
+				seen[link] = true
+				queue = append(queue, link)
+			}
+		}
+	}
+
+	return seen
+}
+
+// Function to count the distinct pages linking to each URL
+func (g *linkGraph) inbound() map[string]int {
+	counts := make(map[string]int)
+	for _, links := range g.out {
+		for _, link := range links {
+			counts[link]++
+		}
+	}
+	return counts
+}
@@ -0,0 +1,115 @@
+package crawler
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/gobwas/glob"
+)
+
+// NormalizeConfig holds the optional steps used to turn URLs into the keys of the visited set
+type NormalizeConfig struct {
+
+	// Sort the query parameters so their order does not matter
+	SortQuery bool `json:"sort_query"`
+
+	// Query parameters to drop, as glob patterns such as "utm_*"
+	DropParams []string `json:"drop_params"`
+
+	// Treat "/dir" and "/dir/" as the same URL
+	IgnoreTrailingSlash bool `json:"ignore_trailing_slash"`
+
+	// Compiled form of DropParams
+	dropGlobs []glob.Glob
+}
+
+// Compiles the patterns of the query parameters to drop
+func (n *NormalizeConfig) compile() error {
+	n.dropGlobs = nil
+	for _, pattern := range n.DropParams {
+		g, err := glob.Compile(pattern)
+		if err != nil {
+			return err
+		}
+		n.dropGlobs = append(n.dropGlobs, g)
+	}
+	return nil
+}
+
+// Checks whether a query parameter should be dropped
+func (n *NormalizeConfig) dropParam(name string) bool {
+	for _, g := range n.dropGlobs {
+		if g.Match(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Function to turn a URL into its canonical form, so that different spellings of one page are visited only once.
+// The fragment, default port and dot segments are always removed and the scheme and host lowercased;
+// everything else depends on the config.
+func (cfg *Config) canonicalURL(URL string) string {
+	parsedURL, err := url.Parse(URL)
+	if err != nil || parsedURL.Opaque != "" {
+		// Nothing to normalize, so only the exact same string counts as a duplicate
+		return URL
+	}
+
+	// Resolving against itself removes the "." and ".." segments
+	parsedURL = parsedURL.ResolveReference(&url.URL{})
+	parsedURL.Fragment = ""
+	parsedURL.RawFragment = ""
+
+	parsedURL.Scheme = strings.ToLower(parsedURL.Scheme)
+	parsedURL.Host = strings.ToLower(parsedURL.Host)
+
+	// Drop the port if it is the default one for the scheme
+	port := parsedURL.Port()
+	if (parsedURL.Scheme == "http" && port == "80") || (parsedURL.Scheme == "https" && port == "443") {
+		parsedURL.Host = strings.TrimSuffix(parsedURL.Host, ":"+port)
+	}
+
+	if parsedURL.Path == "" {
+		parsedURL.Path = "/"
+		parsedURL.RawPath = ""
+	}
+
+	if cfg == nil {
+		return parsedURL.String()
+	}
+
+	if rule := cfg.hostRule(parsedURL.Host); rule != nil && rule.CaseInsensitivePaths {
+		parsedURL.Path = strings.ToLower(parsedURL.Path)
+		parsedURL.RawPath = strings.ToLower(parsedURL.RawPath)
+	}
+
+	if cfg.Normalize.IgnoreTrailingSlash && parsedURL.Path != "/" {
+		parsedURL.Path = strings.TrimSuffix(parsedURL.Path, "/")
+		parsedURL.RawPath = strings.TrimSuffix(parsedURL.RawPath, "/")
+	}
+
+	// Filter and sort the raw query parameters, so the ones that are kept are not re-encoded
+	if parsedURL.RawQuery != "" {
+		var params []string
+		for _, param := range strings.Split(parsedURL.RawQuery, "&") {
+			name, _, _ := strings.Cut(param, "=")
+			if unescaped, err := url.QueryUnescape(name); err == nil {
+				name = unescaped
+			}
+
+			if param != "" && !cfg.Normalize.dropParam(name) {
+				params = append(params, param)
+			}
+		}
+
+		if cfg.Normalize.SortQuery {
+			sort.Strings(params)
+		}
+
+		parsedURL.RawQuery = strings.Join(params, "&")
+	}
+
+	return parsedURL.String()
+}
@@ -0,0 +1,95 @@
+package crawler
+
+import "testing"
+
+func TestConfig_canonicalURL(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Normalize.SortQuery = true
+	cfg.Normalize.DropParams = []string{"utm_*", "fbclid"}
+	cfg.Hosts = append([]HostRule{{DomainGlob: "docs.test", CaseInsensitivePaths: true}}, cfg.Hosts...)
+	if err := cfg.compile(); err != nil {
+		t.Fatalf("compile() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		cfg  *Config
+		URL  string
+		want string
+	}{
+		{
+			name: "Fragment",
+			cfg:  cfg,
+			URL:  "https://kdlp.underground.software/page.html#top",
+			want: "https://kdlp.underground.software/page.html",
+		},
+		{
+			name: "Scheme, host and default port",
+			cfg:  cfg,
+			URL:  "HTTPS://KDLP.underground.software:443/page.html",
+			want: "https://kdlp.underground.software/page.html",
+		},
+		{
+			name: "Non-default port is kept",
+			cfg:  cfg,
+			URL:  "https://kdlp.underground.software:8443/page.html",
+			want: "https://kdlp.underground.software:8443/page.html",
+		},
+		{
+			name: "Dot segments",
+			cfg:  cfg,
+			URL:  "https://kdlp.underground.software/./a/../page.html",
+			want: "https://kdlp.underground.software/page.html",
+		},
+		{
+			name: "Trailing slash",
+			cfg:  cfg,
+			URL:  "https://kdlp.underground.software/dir/",
+			want: "https://kdlp.underground.software/dir",
+		},
+		{
+			name: "Root path",
+			cfg:  cfg,
+			URL:  "https://kdlp.underground.software",
+			want: "https://kdlp.underground.software/",
+		},
+		{
+			name: "Case-insensitive host",
+			cfg:  cfg,
+			URL:  "https://docs.test/PAGE.html",
+			want: "https://docs.test/page.html",
+		},
+		{
+			name: "Case-sensitive host",
+			cfg:  cfg,
+			URL:  "https://kdlp.underground.software/PAGE.html",
+			want: "https://kdlp.underground.software/PAGE.html",
+		},
+		{
+			name: "Sorted query without tracking parameters",
+			cfg:  cfg,
+			URL:  "https://kdlp.underground.software/log?id=2&utm_source=mail&h=master&fbclid=x",
+			want: "https://kdlp.underground.software/log?h=master&id=2",
+		},
+		{
+			name: "Only tracking parameters",
+			cfg:  cfg,
+			URL:  "https://kdlp.underground.software/page.html?utm_source=mail",
+			want: "https://kdlp.underground.software/page.html",
+		},
+		{
+			name: "No config keeps query and trailing slash",
+			cfg:  nil,
+			URL:  "https://kdlp.underground.software/dir/?b=1&a=2#top",
+			want: "https://kdlp.underground.software/dir/?b=1&a=2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.cfg.canonicalURL(tt.URL); got != tt.want {
+				t.Errorf("canonicalURL(%q) = %q, want %q", tt.URL, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package crawler
+
+import (
+	"sync"
+	"time"
+)
+
+// Observer is told what a crawl does as it goes, by both the custom and the colly engine.
+// Calls to an observer never overlap, even when the engine crawls concurrently,
+// so observers do not need their own locking. Embed NopObserver to implement only some hooks.
+type Observer interface {
+
+	// OnPageFetched is called for every page fetched to be scanned for links
+	OnPageFetched(page Page)
+
+	// OnLinkDiscovered is called for every link found on a page, visited before or not
+	OnLinkDiscovered(link Link)
+
+	// OnLinkChecked is called with the status of every URL checked, pages included
+	OnLinkChecked(check LinkCheck)
+
+	// OnFinding is called for every problem found, as soon as it is found
+	OnFinding(finding Finding)
+}
+
+// Page is a page fetched to be scanned for links
+type Page struct {
+	URL         string
+	StatusCode  int
+	ContentType string
+
+	// Number of bytes of the body that were read
+	Size int64
+}
+
+// Link is a link found on a page
+type Link struct {
+	URL string
+
+	// Page the link was found on
+	ReferringURL string
+
+	// Number of links followed from the home page to get to the link
+	Depth int
+}
+
+// LinkCheck is the outcome of checking a URL
+type LinkCheck struct {
+	URL string
+
+	// Page the URL was found on, empty for the home page or when the engine does not know it
+	ReferringURL string
+
+	// Status code of the response, 0 if there was none
+	StatusCode int
+
+	// Why the URL could not be checked, nil if it could
+	Err error
+
+	// Time from sending the request to getting the response, not counting the wait for the rate limit
+	Duration time.Duration
+
+	// Number of requests sent again, such as a GET after the server refused HEAD
+	Retries int
+}
+
+// FindingKind tells what kind of problem a finding is
+type FindingKind string
+
+const (
+	// A link to a URL that answered 404
+	FindingDeadLink FindingKind = "dead_link"
+
+	// A link to an error page served with a 2xx status
+	FindingSoft404 FindingKind = "soft_404"
+
+	// A sitemap entry that cannot be reached by following links from the home page
+	FindingSitemapOrphan FindingKind = "sitemap_orphan"
+
+	// A page reachable by links that is missing from the sitemap
+	FindingMissingFromSitemap FindingKind = "missing_from_sitemap"
+
+	// A known page that cannot be reached by following links from the home page
+	FindingOrphan FindingKind = "orphan"
+)
+
+// Finding is a problem found by a crawl
+type Finding struct {
+	Kind FindingKind
+	URL  string
+
+	// Page or sitemap the URL was found on, empty when there is none
+	ReferringURL string
+
+	// Status code of the URL, 0 when it does not apply
+	StatusCode int
+
+	// Whether the URL belongs to the crawled site
+	Internal bool
+}
+
+// NopObserver does nothing, embed it in an observer to implement only the hooks it needs
+type NopObserver struct{}
+
+func (NopObserver) OnPageFetched(Page)      {}
+func (NopObserver) OnLinkDiscovered(Link)   {}
+func (NopObserver) OnLinkChecked(LinkCheck) {}
+func (NopObserver) OnFinding(Finding)       {}
+
+// ObserverFuncs is an Observer made of functions, any of which may be nil
+type ObserverFuncs struct {
+	PageFetched    func(page Page)
+	LinkDiscovered func(link Link)
+	LinkChecked    func(check LinkCheck)
+	Finding        func(finding Finding)
+}
+
+func (o ObserverFuncs) OnPageFetched(page Page) {
+	if o.PageFetched != nil {
+		o.PageFetched(page)
+	}
+}
+
+func (o ObserverFuncs) OnLinkDiscovered(link Link) {
+	if o.LinkDiscovered != nil {
+		o.LinkDiscovered(link)
+	}
+}
+
+func (o ObserverFuncs) OnLinkChecked(check LinkCheck) {
+	if o.LinkChecked != nil {
+		o.LinkChecked(check)
+	}
+}
+
+func (o ObserverFuncs) OnFinding(finding Finding) {
+	if o.Finding != nil {
+		o.Finding(finding)
+	}
+}
+
+// Passes events on to every observer of a crawl, one event at a time
+type observerList struct {
+	mu        sync.Mutex
+	observers []Observer
+}
+
+// Function to create the list of observers of a crawl
+func newObserverList(observers []Observer) *observerList {
+	return &observerList{observers: observers}
+}
+
+// Calls fn for every observer while holding the lock, a nil list has no observers
+func (l *observerList) each(fn func(o Observer)) {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, o := range l.observers {
+		fn(o)
+	}
+}
+
+func (l *observerList) pageFetched(page Page) {
+	l.each(func(o Observer) { o.OnPageFetched(page) })
+}
+
+func (l *observerList) linkDiscovered(link Link) {
+	l.each(func(o Observer) { o.OnLinkDiscovered(link) })
+}
+
+func (l *observerList) linkChecked(check LinkCheck) {
+	l.each(func(o Observer) { o.OnLinkChecked(check) })
+}
+
+func (l *observerList) finding(finding Finding) {
+	l.each(func(o Observer) { o.OnFinding(finding) })
+}
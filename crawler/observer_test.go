@@ -0,0 +1,92 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// Observer that records the URLs of the events it is told about
+type recordingObserver struct {
+	NopObserver
+	pages      []string
+	discovered []string
+	findings   []string
+}
+
+func (o *recordingObserver) OnPageFetched(page Page) {
+	o.pages = append(o.pages, page.URL)
+}
+
+func (o *recordingObserver) OnLinkDiscovered(link Link) {
+	o.discovered = append(o.discovered, link.URL)
+}
+
+func (o *recordingObserver) OnFinding(finding Finding) {
+	o.findings = append(o.findings, string(finding.Kind)+" "+finding.URL)
+}
+
+// Sorts the recorded URLs, colly visits pages in no particular order
+func (o *recordingObserver) sorted() *recordingObserver {
+	for _, urls := range [][]string{o.pages, o.discovered, o.findings} {
+		sort.Strings(urls)
+	}
+	return o
+}
+
+// Site used by the engine tests: the home page links to a page, a missing page and a page to exclude
+func observedSite() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprint(w, `<html><body><a href="/page.html">Page</a> <a href="/missing.html">Missing</a> <a href="/excluded.html">Excluded</a></body></html>`)
+		case "/page.html":
+			fmt.Fprint(w, `<html><body>content</body></html>`)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+}
+
+func Test_observers(t *testing.T) {
+	server := httptest.NewTLSServer(observedSite())
+	defer server.Close()
+
+	cfg := &Config{
+		Hosts:       []HostRule{{DomainGlob: "*", IgnoreRobots: true, InsecureSkipVerify: true}},
+		MaxPageSize: defaultMaxPageSize,
+		Exclude:     []string{"*/excluded*"},
+	}
+	if err := cfg.compile(); err != nil {
+		t.Fatalf("compile() error = %v", err)
+	}
+
+	// Both engines should tell observers the same story about the same site
+	want := &recordingObserver{
+		pages:      []string{server.URL + "/", server.URL + "/page.html"},
+		discovered: []string{server.URL + "/excluded.html", server.URL + "/missing.html", server.URL + "/page.html"},
+		findings:   []string{"dead_link " + server.URL + "/missing.html"},
+	}
+
+	for name, engine := range engines {
+		t.Run(name, func(t *testing.T) {
+			got := &recordingObserver{}
+			_, err := engine.Crawl(context.Background(), Options{
+				Domain:    server.URL + "/",
+				HomeURL:   server.URL + "/",
+				Config:    cfg,
+				Observers: []Observer{got},
+			})
+			if err != nil {
+				t.Fatalf("Crawl() error = %v", err)
+			}
+			if !reflect.DeepEqual(got.sorted(), want) {
+				t.Errorf("Crawl() told observers %+v, want %+v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package crawler
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// OrphanPage is a known page that cannot be reached by following links from the home page
+type OrphanPage struct {
+	URL string
+
+	// Number of crawled pages linking to it, those pages are unreachable themselves
+	Inbound int
+}
+
+// Function to list the pages the site is expected to have, as URLs on the crawled domain.
+// location is either the site's source directory, whose files map to the same paths under the domain,
+// or a file listing one URL or path per line.
+func loadKnownPages(location, domain string) ([]string, error) {
+	info, err := os.Stat(location)
+	if err != nil {
+		return nil, err
+	}
+
+	var pages []string
+	if info.IsDir() {
+		pages, err = listSourcePages(location, domain)
+	} else {
+		pages, err = readPageList(location, domain)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(pages)
+	return pages, nil
+}
+
+// Walks a source directory and turns every page file into its URL, skipping hidden files such as .git
+func listSourcePages(dir, domain string) ([]string, error) {
+	var pages []string
+
+	err := filepath.WalkDir(dir, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if strings.HasPrefix(entry.Name(), ".") && filePath != dir {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if entry.IsDir() {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(dir, filePath)
+		if err != nil {
+			return err
+		}
+
+		// Build the reference from a path so characters such as '#' or '?' in file names stay part of it
+		pageURL, err := resolveURL(domain, (&url.URL{Path: filepath.ToSlash(relPath)}).String())
+		if err != nil {
+			return err
+		}
+
+		// Images, PDFs and the like are files, not pages
+		if isPageURL(pageURL) {
+			pages = append(pages, pageURL)
+		}
+		return nil
+	})
+
+	return pages, err
+}
+
+// Reads a listing of pages, one URL or path per line, ignoring blank lines and # comments
+func readPageList(filePath, domain string) ([]string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var pages []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		pageURL, err := resolveURL(domain, line)
+		if err != nil {
+			return nil, fmt.Errorf("invalid page %q in %s: %w", line, filePath, err)
+		}
+		pages = append(pages, pageURL)
+	}
+
+	return pages, scanner.Err()
+}
+
+// Function to find the known pages that cannot be reached by following links from the home page
+func (c *crawler) findOrphans(knownPages []string) []OrphanPage {
+	reachable := c.graph.reachable(c.config.canonicalURL(c.homeURL))
+	inbound := c.graph.inbound()
+
+	var orphans []OrphanPage
+	for _, page := range knownPages {
+		canonical := c.config.canonicalURL(page)
+		if reachable[canonical] {
+			continue
+		}
+
+		// An index page is usually linked as its directory
+		if name := path.Base(page); name == "index.html" || name == "index.htm" {
+			if reachable[c.config.canonicalURL(strings.TrimSuffix(page, name))] {
+				continue
+			}
+		}
+
+		orphans = append(orphans, OrphanPage{URL: page, Inbound: inbound[canonical]})
+	}
+
+	return orphans
+}
+
+// String describes the orphan the way orphans.txt lists it
+func (o OrphanPage) String() string {
+	return fmt.Sprintf("orphan page %s (%d inbound links)", o.URL, o.Inbound)
+}
@@ -0,0 +1,123 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func Test_loadKnownPages(t *testing.T) {
+	// A site source directory with pages, an image and a hidden directory
+	dir := t.TempDir()
+	for _, name := range []string{"index.md", "about.html", "notes/week1.md", "images/logo.png", ".git/HEAD"} {
+		filePath := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filePath, []byte("content"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A listing mixing paths, absolute URLs, comments and blank lines
+	listing := filepath.Join(t.TempDir(), "pages.txt")
+	if err := os.WriteFile(listing, []byte("# Expected pages\n/index.md\n\nnotes/week1.md\nhttps://kdlp.underground.software/about.html\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		location string
+		want     []string
+		wantErr  bool
+	}{
+		{
+			name:     "Source directory",
+			location: dir,
+			want: []string{
+				"https://kdlp.underground.software/about.html",
+				"https://kdlp.underground.software/index.md",
+				"https://kdlp.underground.software/notes/week1.md",
+			},
+		},
+		{
+			name:     "Page listing",
+			location: listing,
+			want: []string{
+				"https://kdlp.underground.software/about.html",
+				"https://kdlp.underground.software/index.md",
+				"https://kdlp.underground.software/notes/week1.md",
+			},
+		},
+		{
+			name:     "Missing location",
+			location: filepath.Join(dir, "missing"),
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := loadKnownPages(tt.location, "https://kdlp.underground.software/")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadKnownPages() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("loadKnownPages() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCrawler_findOrphans(t *testing.T) {
+	// The home page links to the docs directory, whose index is known as docs/index.html.
+	// hidden.html is only linked from island.html, which nothing links to.
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/index.html":
+			fmt.Fprint(w, `<a href="docs/">Docs</a>`)
+		case "/docs/":
+			fmt.Fprint(w, "docs")
+		case "/island.html":
+			fmt.Fprint(w, `<a href="hidden.html">Hidden</a>`)
+		case "/hidden.html":
+			fmt.Fprint(w, "hidden")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	cfg := &Config{Hosts: []HostRule{{DomainGlob: "*", IgnoreRobots: true}}}
+	if err := cfg.compile(); err != nil {
+		t.Fatalf("compile() error = %v", err)
+	}
+	c, err := newCrawler(server.URL+"/", server.URL+"/index.html", cfg)
+	if err != nil {
+		t.Fatalf("newCrawler() error = %v", err)
+	}
+
+	// Seed the crawl with island.html the way a sitemap would, so its links end up in the link graph
+	c.sitemapPages = map[string]string{server.URL + "/island.html": server.URL + "/sitemap.xml"}
+	c.crawl(context.Background(), c.homeURL)
+
+	knownPages := []string{
+		server.URL + "/docs/index.html",
+		server.URL + "/hidden.html",
+		server.URL + "/index.html",
+		server.URL + "/island.html",
+	}
+	got := c.findOrphans(knownPages)
+	want := []OrphanPage{
+		{URL: server.URL + "/hidden.html", Inbound: 1},
+		{URL: server.URL + "/island.html", Inbound: 0},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("findOrphans() = %v, want %v", got, want)
+	}
+}
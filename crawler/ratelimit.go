@@ -0,0 +1,127 @@
+package crawler
+
+import (
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/gocolly/colly/v2"
+)
+
+// Limits the number of concurrent requests and the request rate per host
+type hostLimiter struct {
+	config *Config
+
+	// Protects hosts
+	mu sync.Mutex
+
+	// State for every host seen so far
+	hosts map[string]*hostState
+}
+
+// Rate limiting state of a single host
+type hostState struct {
+
+	// Holds one token per request in flight, nil when unlimited
+	slots chan struct{}
+
+	// Protects last and delay
+	mu sync.Mutex
+
+	// Start time of the last request
+	last time.Time
+
+	// Minimum time between the start of two requests
+	delay time.Duration
+}
+
+// Function to create a new limiter applying the host rules of cfg
+func newHostLimiter(cfg *Config) *hostLimiter {
+	return &hostLimiter{
+		config: cfg,
+		hosts:  make(map[string]*hostState),
+	}
+}
+
+// Returns the state for host, creating it from the matching rule on first use
+func (l *hostLimiter) state(host string) *hostState {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if s, ok := l.hosts[host]; ok {
+		return s
+	}
+
+	s := &hostState{}
+	if rule := l.config.hostRule(host); rule != nil {
+		if rule.Parallelism > 0 {
+			s.slots = make(chan struct{}, rule.Parallelism)
+		}
+		s.delay = rule.Delay.Duration
+	}
+	l.hosts[host] = s
+
+	return s
+}
+
+// Blocks until a request to URL is allowed and returns the function to call once it is done
+func (l *hostLimiter) acquire(URL string) func() {
+	if l == nil {
+		return func() {}
+	}
+
+	parsedURL, err := url.Parse(URL)
+	if err != nil {
+		// The request will fail on its own, no need to hold it back
+		return func() {}
+	}
+
+	s := l.state(parsedURL.Host)
+
+	// Wait for a free slot
+	if s.slots != nil {
+		s.slots <- struct{}{}
+	}
+
+	// Wait until enough time has passed since the last request started
+	s.mu.Lock()
+	if wait := time.Until(s.last.Add(s.delay)); wait > 0 {
+		time.Sleep(wait)
+	}
+	s.last = time.Now()
+	s.mu.Unlock()
+
+	return func() {
+		if s.slots != nil {
+			<-s.slots
+		}
+	}
+}
+
+// Raises the minimum time between requests to host to at least delay
+func (l *hostLimiter) setMinDelay(host string, delay time.Duration) {
+	if l == nil {
+		return
+	}
+
+	s := l.state(host)
+
+	s.mu.Lock()
+	if delay > s.delay {
+		s.delay = delay
+	}
+	s.mu.Unlock()
+}
+
+// Converts the host rules into colly limit rules
+func (cfg *Config) collyLimitRules() []*colly.LimitRule {
+	var rules []*colly.LimitRule
+	for _, rule := range cfg.Hosts {
+		rules = append(rules, &colly.LimitRule{
+			DomainGlob:  rule.DomainGlob,
+			Parallelism: rule.Parallelism,
+			Delay:       rule.Delay.Duration,
+		})
+	}
+	return rules
+}
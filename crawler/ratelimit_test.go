@@ -0,0 +1,105 @@
+package crawler
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func Test_hostLimiter_acquire(t *testing.T) {
+	cfg := &Config{
+		Hosts: []HostRule{
+			{DomainGlob: "slow.test", Parallelism: 1, Delay: Duration{50 * time.Millisecond}},
+			{DomainGlob: "*"},
+		},
+	}
+	if err := cfg.compile(); err != nil {
+		t.Fatalf("compile() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		URL      string
+		requests int
+		minTotal time.Duration
+		maxTotal time.Duration
+	}{
+		{
+			name:     "Delayed host",
+			URL:      "https://slow.test/page",
+			requests: 3,
+			minTotal: 100 * time.Millisecond,
+			maxTotal: time.Second,
+		},
+		{
+			name:     "Unlimited host",
+			URL:      "https://fast.test/page",
+			requests: 3,
+			minTotal: 0,
+			maxTotal: 50 * time.Millisecond,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newHostLimiter(cfg)
+			start := time.Now()
+
+			for i := 0; i < tt.requests; i++ {
+				release := l.acquire(tt.URL)
+				release()
+			}
+
+			elapsed := time.Since(start)
+			if elapsed < tt.minTotal || elapsed > tt.maxTotal {
+				t.Errorf("%d requests took %v, want between %v and %v", tt.requests, elapsed, tt.minTotal, tt.maxTotal)
+			}
+		})
+	}
+}
+
+func Test_hostLimiter_parallelism(t *testing.T) {
+	cfg := &Config{Hosts: []HostRule{{DomainGlob: "*", Parallelism: 2}}}
+	if err := cfg.compile(); err != nil {
+		t.Fatalf("compile() error = %v", err)
+	}
+	l := newHostLimiter(cfg)
+
+	var mu sync.Mutex
+	inFlight, maxInFlight := 0, 0
+
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release := l.acquire("https://example.test/")
+			defer release()
+
+			mu.Lock()
+			inFlight++
+			if inFlight > maxInFlight {
+				maxInFlight = inFlight
+			}
+			mu.Unlock()
+
+			time.Sleep(10 * time.Millisecond)
+
+			mu.Lock()
+			inFlight--
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	if maxInFlight > 2 {
+		t.Errorf("saw %d requests in flight, want at most 2", maxInFlight)
+	}
+}
+
+func Test_hostLimiter_nil(t *testing.T) {
+	// A crawler built without a limiter must still be able to fetch
+	var l *hostLimiter
+	release := l.acquire("https://example.test/")
+	release()
+}
@@ -0,0 +1,157 @@
+package crawler
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Exit codes of a crawl, the same whichever engine ran it
+const (
+	// Nothing wrong was found
+	ExitOK = 0
+
+	// The crawl finished and found dead links or other problems
+	ExitFindings = 1
+
+	// The crawl could not be run or was cut short
+	ExitError = 2
+)
+
+// ExitCode returns the exit code for the outcome of a crawl, as returned by an Engine
+func ExitCode(result *Result, err error) int {
+	if err != nil || result == nil {
+		return ExitError
+	}
+	if result.hasFindings() {
+		return ExitFindings
+	}
+	return ExitOK
+}
+
+// Checks whether a crawl found any problem
+func (r *Result) hasFindings() bool {
+	if len(r.DeadLinks) > 0 || len(r.Orphans) > 0 {
+		return true
+	}
+	return r.Sitemap != nil && len(r.Sitemap.Orphans)+len(r.Sitemap.Missing)+len(r.Sitemap.Dead) > 0
+}
+
+// Reporter presents the result of a crawl
+type Reporter interface {
+	Report(result *Result) error
+}
+
+// TextReporter writes the result to text files in Dir, the current directory if empty, and prints
+// a summary to Out, standard output if nil. It writes the files the command line always has:
+// dead_links.txt, sitemap_report.txt and orphans.txt, each only when there is something to put in it.
+type TextReporter struct {
+	Dir string
+	Out io.Writer
+}
+
+// Report writes the result's files and summary
+func (t TextReporter) Report(result *Result) error {
+	out := t.Out
+	if out == nil {
+		out = os.Stdout
+	}
+
+	// Display the limits that ended or trimmed the crawl
+	for _, line := range result.BudgetSummary {
+		fmt.Fprintln(out, line)
+	}
+
+	// Check if there are any dead links
+	if len(result.DeadLinks) > 0 {
+		lines := make([]string, len(result.DeadLinks))
+		for i, link := range result.DeadLinks {
+			lines[i] = link.String()
+		}
+		if err := WriteLines(filepath.Join(t.Dir, "dead_links.txt"), lines); err != nil {
+			return fmt.Errorf("failed to save dead links: %w", err)
+		}
+
+		// Display file path for dead links
+		fmt.Fprintln(out, "Dead links written to: dead_links.txt")
+	} else {
+		// Display no dead links found in the terminal, no dead links file is created
+		fmt.Fprintln(out, "No dead links found")
+	}
+
+	// Compare the sitemap with what the links lead to
+	if report := result.Sitemap; report != nil {
+		fmt.Fprintln(out, "Sitemap:", len(report.Orphans), "orphaned,", len(report.Missing), "missing,", len(report.Dead), "dead")
+		if err := WriteLines(filepath.Join(t.Dir, "sitemap_report.txt"), report.Lines()); err != nil {
+			return fmt.Errorf("failed to save sitemap report: %w", err)
+		}
+		fmt.Fprintln(out, "Sitemap report written to: sitemap_report.txt")
+	}
+
+	// Report the known pages that no link leads to
+	if len(result.Orphans) > 0 {
+		lines := make([]string, len(result.Orphans))
+		for i, orphan := range result.Orphans {
+			lines[i] = orphan.String()
+		}
+		if err := WriteLines(filepath.Join(t.Dir, "orphans.txt"), lines); err != nil {
+			return fmt.Errorf("failed to save orphan pages: %w", err)
+		}
+		fmt.Fprintln(out, len(result.Orphans), "orphan pages written to: orphans.txt")
+	}
+
+	// Display the elapsed time
+	fmt.Fprintln(out, "Elapsed time:", result.Elapsed)
+	return nil
+}
+
+// WriteLines writes lines to the file at path, one per line, replacing what the file held before
+func WriteLines(path string, lines []string) error {
+
+	// Open the file in write-only mode
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// Write each line to the file
+	for _, line := range lines {
+		if _, err := fmt.Fprintln(file, line); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DeadLinkFile is an observer that rewrites a file with the dead links found so far every time it
+// finds one, so an interrupted crawl still leaves them behind
+type DeadLinkFile struct {
+	NopObserver
+
+	// File to write, one dead link per line
+	Path string
+
+	mu    sync.Mutex
+	lines []string
+}
+
+// OnFinding adds dead links and soft 404s to the file
+func (d *DeadLinkFile) OnFinding(finding Finding) {
+	if finding.Kind != FindingDeadLink && finding.Kind != FindingSoft404 {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	link := DeadLink{URL: finding.URL, ReferringURL: finding.ReferringURL, StatusCode: finding.StatusCode}
+	d.lines = append(d.lines, link.String())
+	if err := WriteLines(d.Path, d.lines); err != nil {
+		log.Println("Error saving dead links to file:", err)
+	}
+}
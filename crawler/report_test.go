@@ -0,0 +1,164 @@
+package crawler
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_WriteLines(t *testing.T) {
+	type args struct {
+		deadLinks []string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr bool
+	}{
+		{
+			name: "Test writing multiple dead links",
+			args: args{
+				deadLinks: []string{
+					"https://www.example.com/deadlink1",
+					"https://www.example.com/deadlink2",
+					"https://www.example.com/deadlink3",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Test writing single dead link",
+			args: args{
+				deadLinks: []string{
+					"https://www.example.com/deadlink4",
+				},
+			},
+			wantErr: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Call the function being tested
+			filepath := "test_dead_links.txt"
+			err := WriteLines(filepath, tt.args.deadLinks)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("WriteLines() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	t.Cleanup(func() {
+		if err := os.Remove("test_dead_links.txt"); err != nil {
+			t.Fatalf("Error removing test_dead_links.txt: %v", err)
+		}
+	})
+}
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name   string
+		result *Result
+		err    error
+		want   int
+	}{
+		{
+			name:   "Nothing found",
+			result: &Result{},
+			want:   ExitOK,
+		},
+		{
+			name:   "Dead links",
+			result: &Result{DeadLinks: []DeadLink{{URL: "https://example.com/missing"}}},
+			want:   ExitFindings,
+		},
+		{
+			name:   "Pages missing from the sitemap",
+			result: &Result{Sitemap: &SitemapReport{Missing: []string{"https://example.com/new"}}},
+			want:   ExitFindings,
+		},
+		{
+			name:   "Clean sitemap",
+			result: &Result{Sitemap: &SitemapReport{}},
+			want:   ExitOK,
+		},
+		{
+			name:   "Interrupted crawl",
+			result: &Result{},
+			err:    errors.New("context canceled"),
+			want:   ExitError,
+		},
+		{
+			name: "Crawl that could not start",
+			err:  errors.New("unknown crawl order"),
+			want: ExitError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExitCode(tt.result, tt.err); got != tt.want {
+				t.Errorf("ExitCode() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTextReporter_Report(t *testing.T) {
+	dir := t.TempDir()
+	var out strings.Builder
+
+	result := &Result{
+		DeadLinks: []DeadLink{
+			{URL: "https://example.com/missing", ReferringURL: "https://example.com/", StatusCode: 404},
+			{URL: "https://example.com/gone", StatusCode: 404},
+		},
+		Orphans: []OrphanPage{{URL: "https://example.com/hidden", Inbound: 1}},
+	}
+	if err := (TextReporter{Dir: dir, Out: &out}).Report(result); err != nil {
+		t.Fatalf("Report() error = %v", err)
+	}
+
+	files := map[string]string{
+		"dead_links.txt": "dead link https://example.com/missing found at: https://example.com/\ndead link https://example.com/gone\n",
+		"orphans.txt":    "orphan page https://example.com/hidden (1 inbound links)\n",
+	}
+	for name, want := range files {
+		got, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("Report() did not write %s: %v", name, err)
+		}
+		if string(got) != want {
+			t.Errorf("Report() wrote %s = %q, want %q", name, got, want)
+		}
+	}
+
+	// Without a sitemap there is no sitemap report
+	if _, err := os.Stat(filepath.Join(dir, "sitemap_report.txt")); !os.IsNotExist(err) {
+		t.Errorf("Report() wrote sitemap_report.txt without a sitemap")
+	}
+
+	if !strings.Contains(out.String(), "Dead links written to: dead_links.txt") {
+		t.Errorf("Report() printed %q, want the dead links file mentioned", out.String())
+	}
+}
+
+func TestDeadLinkFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dead_links.txt")
+	d := &DeadLinkFile{Path: path}
+
+	d.OnFinding(Finding{Kind: FindingDeadLink, URL: "https://example.com/missing", ReferringURL: "https://example.com/", StatusCode: 404})
+	d.OnFinding(Finding{Kind: FindingOrphan, URL: "https://example.com/hidden"})
+	d.OnFinding(Finding{Kind: FindingSoft404, URL: "https://example.com/old", ReferringURL: "https://example.com/", StatusCode: 200})
+
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "dead link https://example.com/missing found at: https://example.com/\ndead link https://example.com/old found at: https://example.com/\n"
+	if string(got) != want {
+		t.Errorf("DeadLinkFile wrote %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,114 @@
+package crawler
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"github.com/temoto/robotstxt"
+)
+
+// Name the crawler looks for in robots.txt user-agent groups
+const robotsUserAgent = "KDLP_Webcrawler"
+
+// Fetches, caches and applies the robots.txt of every host the crawler visits
+type robotsCache struct {
+	config *Config
+	client *http.Client
+
+	// Crawl-delay values are passed on to the limiter
+	limiter *hostLimiter
+
+	// Protects hosts, but not the entries in it
+	mu sync.Mutex
+
+	// Robots.txt per scheme and host
+	hosts map[string]*robotsEntry
+}
+
+// Robots.txt of one host, fetched once by whoever needs it first
+type robotsEntry struct {
+	once   sync.Once
+	robots *robotstxt.RobotsData
+}
+
+// Function to create a new robots.txt cache
+func newRobotsCache(cfg *Config, client *http.Client, limiter *hostLimiter) *robotsCache {
+	return &robotsCache{
+		config:  cfg,
+		client:  client,
+		limiter: limiter,
+		hosts:   make(map[string]*robotsEntry),
+	}
+}
+
+// Checks whether the robots.txt of the URL's host allows crawling it
+func (r *robotsCache) allowed(ctx context.Context, URL string) bool {
+	if r == nil {
+		return true
+	}
+
+	parsedURL, err := url.Parse(URL)
+	if err != nil {
+		return true
+	}
+
+	// Hosts we own can opt out of robots.txt entirely
+	if rule := r.config.hostRule(parsedURL.Host); rule != nil && rule.IgnoreRobots {
+		return true
+	}
+
+	robots := r.lookup(ctx, parsedURL)
+	return robots.TestAgent(parsedURL.EscapedPath(), robotsUserAgent)
+}
+
+// Returns the robots.txt for the URL's host, fetching it on first use
+func (r *robotsCache) lookup(ctx context.Context, parsedURL *url.URL) *robotstxt.RobotsData {
+	key := parsedURL.Scheme + "://" + parsedURL.Host
+
+	// Only hold the cache's lock to find the host's entry, so a slow host does not hold up the others
+	r.mu.Lock()
+	entry, ok := r.hosts[key]
+	if !ok {
+		entry = &robotsEntry{}
+		r.hosts[key] = entry
+	}
+	r.mu.Unlock()
+
+	// Requests to the same host wait for the first one to fetch its robots.txt
+	entry.once.Do(func() {
+		entry.robots = r.fetch(ctx, key+"/robots.txt")
+
+		// Honor Crawl-delay by slowing down all further requests to the host
+		if delay := entry.robots.FindGroup(robotsUserAgent).CrawlDelay; delay > 0 {
+			r.limiter.setMinDelay(parsedURL.Host, delay)
+		}
+	})
+
+	return entry.robots
+}
+
+// Function to fetch and parse a robots.txt file
+func (r *robotsCache) fetch(ctx context.Context, robotsURL string) *robotstxt.RobotsData {
+	release := r.limiter.acquire(robotsURL)
+	defer release()
+
+	resp, err := fetchHTTPResponse(ctx, r.client, robotsURL)
+	if err != nil {
+		// A host we cannot reach has no rules we could follow
+		log.Println("Error fetching robots.txt:", robotsURL, "Error:", err)
+		robots, _ := robotstxt.FromStatusAndBytes(http.StatusNotFound, nil)
+		return robots
+	}
+	defer resp.Body.Close()
+
+	robots, err := robotstxt.FromResponse(resp)
+	if err != nil {
+		log.Println("Error parsing robots.txt:", robotsURL, "Error:", err)
+		robots, _ = robotstxt.FromStatusAndBytes(http.StatusNotFound, nil)
+	}
+
+	return robots
+}
@@ -0,0 +1,147 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Starts a test server with the given robots.txt and counts how often it is fetched
+func newRobotsServer(t *testing.T, robots string, fetches *int) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			*fetches++
+			fmt.Fprint(w, robots)
+			return
+		}
+		fmt.Fprint(w, "<html></html>")
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func Test_robotsCache_allowed(t *testing.T) {
+	robots := "User-agent: *\nDisallow: /private\n\nUser-agent: KDLP_Webcrawler\nDisallow: /secret\n"
+
+	tests := []struct {
+		name         string
+		path         string
+		ignoreRobots bool
+		want         bool
+	}{
+		{name: "Allowed path", path: "/index.html", want: true},
+		{name: "Path disallowed for our user agent", path: "/secret/page.html", want: false},
+		{name: "Path only disallowed for other agents", path: "/private/page.html", want: true},
+		{name: "Host opted out of robots.txt", path: "/secret/page.html", ignoreRobots: true, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fetches := 0
+			server := newRobotsServer(t, robots, &fetches)
+
+			cfg := &Config{Hosts: []HostRule{{DomainGlob: "*", IgnoreRobots: tt.ignoreRobots}}}
+			if err := cfg.compile(); err != nil {
+				t.Fatalf("compile() error = %v", err)
+			}
+			r := newRobotsCache(cfg, http.DefaultClient, newHostLimiter(cfg))
+
+			// Ask twice, robots.txt must only be fetched once
+			for i := 0; i < 2; i++ {
+				if got := r.allowed(context.Background(), server.URL+tt.path); got != tt.want {
+					t.Errorf("allowed(%q) = %v, want %v", tt.path, got, tt.want)
+				}
+			}
+
+			wantFetches := 1
+			if tt.ignoreRobots {
+				wantFetches = 0
+			}
+			if fetches != wantFetches {
+				t.Errorf("robots.txt fetched %d times, want %d", fetches, wantFetches)
+			}
+		})
+	}
+}
+
+func Test_robotsCache_crawlDelay(t *testing.T) {
+	fetches := 0
+	server := newRobotsServer(t, "User-agent: *\nCrawl-delay: 2\n", &fetches)
+
+	cfg := &Config{Hosts: []HostRule{{DomainGlob: "*"}}}
+	if err := cfg.compile(); err != nil {
+		t.Fatalf("compile() error = %v", err)
+	}
+	limiter := newHostLimiter(cfg)
+	r := newRobotsCache(cfg, http.DefaultClient, limiter)
+
+	if !r.allowed(context.Background(), server.URL+"/index.html") {
+		t.Fatalf("allowed() = false, want true")
+	}
+
+	serverURL, _ := url.Parse(server.URL)
+	if got := limiter.state(serverURL.Host).delay; got != 2*time.Second {
+		t.Errorf("host delay = %v, want %v", got, 2*time.Second)
+	}
+}
+
+func Test_robotsCache_slowHost(t *testing.T) {
+	// The slow host answers robots.txt once the test lets it, and counts how often it is asked
+	var slowFetches int32
+	unblock := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&slowFetches, 1)
+		<-unblock
+		http.NotFound(w, r)
+	}))
+	defer slow.Close()
+	defer close(unblock)
+
+	fetches := 0
+	fast := newRobotsServer(t, "User-agent: *\nDisallow: /secret\n", &fetches)
+
+	cfg := &Config{Hosts: []HostRule{{DomainGlob: "*"}}}
+	if err := cfg.compile(); err != nil {
+		t.Fatalf("compile() error = %v", err)
+	}
+	r := newRobotsCache(cfg, http.DefaultClient, newHostLimiter(cfg))
+
+	// Many pages of the slow host wait for its robots.txt at once
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.allowed(context.Background(), slow.URL+"/index.html")
+		}()
+	}
+
+	// Wait until the slow host is being asked
+	for atomic.LoadInt32(&slowFetches) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// Meanwhile the fast host is answered right away
+	done := make(chan bool)
+	go func() { done <- r.allowed(context.Background(), fast.URL+"/secret/page.html") }()
+	select {
+	case got := <-done:
+		if got {
+			t.Errorf("allowed() = true, want false")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("allowed() for a fast host waited on a slow host's robots.txt")
+	}
+
+	unblock <- struct{}{}
+	wg.Wait()
+	if got := atomic.LoadInt32(&slowFetches); got != 1 {
+		t.Errorf("slow robots.txt fetched %d times, want 1", got)
+	}
+}
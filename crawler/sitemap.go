@@ -0,0 +1,216 @@
+package crawler
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strings"
+)
+
+// Sitemap indexes may point to more indexes, but not endlessly
+const maxSitemapNesting = 3
+
+// Function to read a sitemap or sitemap index, returning whether it is an index and the URLs in its <loc> elements
+func parseSitemap(r io.Reader, baseURL string) (bool, []string, error) {
+	var locs []string
+	isIndex := false
+	root := true
+	inLoc := false
+
+	decoder := xml.NewDecoder(r)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return isIndex, locs, nil
+		}
+		if err != nil {
+			return isIndex, locs, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			// The root element tells a list of pages (<urlset>) from a list of sitemaps (<sitemapindex>)
+			if root {
+				isIndex = t.Name.Local == "sitemapindex"
+				root = false
+			}
+			inLoc = t.Name.Local == "loc"
+		case xml.EndElement:
+			inLoc = false
+		case xml.CharData:
+			if inLoc {
+				locs = appendLink(locs, baseURL, strings.TrimSpace(string(t)))
+			}
+		}
+	}
+}
+
+// Function to read the sitemap at location, a URL or a path on the crawled site, so its pages seed the crawl
+func (c *crawler) loadSitemap(ctx context.Context, location string) error {
+	sitemapURL, err := resolveURL(c.homeURL, location)
+	if err != nil {
+		return err
+	}
+
+	pages, err := c.fetchSitemap(ctx, sitemapURL)
+	if err != nil {
+		return err
+	}
+
+	c.logProgress("Found", len(pages), "pages in sitemap:", sitemapURL)
+	c.sitemapPages = pages
+	return nil
+}
+
+// Function to fetch the pages listed in a sitemap, following sitemap indexes and unpacking gzipped sitemaps.
+// Returns each page URL together with the sitemap that listed it.
+func (c *crawler) fetchSitemap(ctx context.Context, sitemapURL string) (map[string]string, error) {
+	pages := make(map[string]string)
+	seen := make(map[string]bool)
+
+	if err := c.fetchSitemapInto(ctx, sitemapURL, pages, seen, 0); err != nil {
+		return nil, err
+	}
+
+	return pages, nil
+}
+
+// Fetches one sitemap and adds its pages, or the pages of the sitemaps it lists, to pages
+func (c *crawler) fetchSitemapInto(ctx context.Context, sitemapURL string, pages map[string]string, seen map[string]bool, nesting int) error {
+	if seen[sitemapURL] {
+		return nil
+	}
+	seen[sitemapURL] = true
+
+	release := c.limiter.acquire(sitemapURL)
+	resp, err := fetchHTTPResponse(ctx, c.client, sitemapURL)
+	release()
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("failed to fetch sitemap %s: received status code %d", sitemapURL, resp.StatusCode)
+	}
+
+	// Gzipped sitemaps are recognized by their magic bytes, whatever their name or Content-Type says
+	body := bufio.NewReader(newCappedReader(resp.Body, c.config.maxPageSize()))
+	var r io.Reader = body
+	if magic, err := body.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			return fmt.Errorf("failed to unpack sitemap %s: %w", sitemapURL, err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	isIndex, locs, err := parseSitemap(r, sitemapURL)
+	if err != nil {
+		return fmt.Errorf("failed to parse sitemap %s: %w", sitemapURL, err)
+	}
+
+	if !isIndex {
+		for _, loc := range locs {
+			if _, ok := pages[loc]; !ok {
+				pages[loc] = sitemapURL
+			}
+		}
+		return nil
+	}
+
+	if nesting >= maxSitemapNesting {
+		return fmt.Errorf("sitemap index %s nested too deeply", sitemapURL)
+	}
+
+	// A broken child sitemap should not hide the pages of the others
+	for _, loc := range locs {
+		if err := c.fetchSitemapInto(ctx, loc, pages, seen, nesting+1); err != nil {
+			log.Println("Error reading sitemap:", err)
+		}
+	}
+
+	return nil
+}
+
+// SitemapReport holds the results of comparing the sitemap with the pages reachable by links
+type SitemapReport struct {
+
+	// In the sitemap but not reachable by following links from the home page
+	Orphans []string
+
+	// Reachable by links but not in the sitemap
+	Missing []string
+
+	// In the sitemap but dead
+	Dead []string
+}
+
+// Function to compare the sitemap pages with the pages reachable by links from the home page
+func (c *crawler) checkSitemap() SitemapReport {
+	var report SitemapReport
+
+	inSitemap := make(map[string]bool)
+	for page := range c.sitemapPages {
+		inSitemap[c.config.canonicalURL(page)] = true
+	}
+
+	reachable := c.graph.reachable(c.config.canonicalURL(c.homeURL))
+
+	for page := range inSitemap {
+		if c.graph.status[page] == 404 {
+			report.Dead = append(report.Dead, c.graph.original(page))
+		} else if !reachable[page] {
+			report.Orphans = append(report.Orphans, c.graph.original(page))
+		}
+	}
+
+	// Only successfully fetched internal pages belong in the sitemap
+	for page := range reachable {
+		statusCode := c.graph.status[page]
+		URL := c.graph.original(page)
+		if !inSitemap[page] && statusCode >= 200 && statusCode < 300 && isInternalURL(URL, c.domain) && isPageURL(URL) {
+			report.Missing = append(report.Missing, URL)
+		}
+	}
+
+	sort.Strings(report.Orphans)
+	sort.Strings(report.Missing)
+	sort.Strings(report.Dead)
+
+	return report
+}
+
+// Lines turns the report into lines of text, as written to sitemap_report.txt
+func (r SitemapReport) Lines() []string {
+	var lines []string
+
+	section := func(title string, URLs []string) {
+		lines = append(lines, fmt.Sprintf("%s (%d):", title, len(URLs)))
+		for _, URL := range URLs {
+			lines = append(lines, "  "+URL)
+		}
+	}
+
+	section("In sitemap but not reachable by links", r.Orphans)
+	section("Reachable by links but missing from sitemap", r.Missing)
+	section("Dead sitemap entries", r.Dead)
+
+	return lines
+}
+
+// Returns the keys of a map in sorted order
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,145 @@
+package crawler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_parseSitemap(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		wantIndex bool
+		wantLocs  []string
+		wantErr   bool
+	}{
+		{
+			name:     "Sitemap",
+			content:  `<?xml version="1.0"?><urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"><url><loc> https://example.com/a.html </loc><lastmod>2024-01-01</lastmod></url><url><loc>/b.html</loc></url></urlset>`,
+			wantLocs: []string{"https://example.com/a.html", "https://example.com/b.html"},
+		},
+		{
+			name:      "Sitemap index",
+			content:   `<sitemapindex><sitemap><loc>https://example.com/pages.xml</loc></sitemap></sitemapindex>`,
+			wantIndex: true,
+			wantLocs:  []string{"https://example.com/pages.xml"},
+		},
+		{
+			name:     "Broken XML",
+			content:  `<urlset><url><loc>https://example.com/a.html</loc></url`,
+			wantLocs: []string{"https://example.com/a.html"},
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			isIndex, locs, err := parseSitemap(strings.NewReader(tt.content), "https://example.com/sitemap.xml")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseSitemap() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if isIndex != tt.wantIndex {
+				t.Errorf("parseSitemap() isIndex = %v, want %v", isIndex, tt.wantIndex)
+			}
+			if !reflect.DeepEqual(locs, tt.wantLocs) {
+				t.Errorf("parseSitemap() locs = %v, want %v", locs, tt.wantLocs)
+			}
+		})
+	}
+}
+
+// Function to gzip a string, for serving gzipped sitemaps
+func gzipString(t *testing.T, s string) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write([]byte(s)); err != nil {
+		t.Fatalf("gzip error = %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("gzip error = %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestCrawler_checkSitemap(t *testing.T) {
+	// The home page links to linked.html and unlisted.html, the sitemap index points to a plain and a gzipped
+	// sitemap which list linked.html, orphan.html and gone.html
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/index.html":
+			fmt.Fprint(w, `<a href="linked.html">Linked</a> <a href="unlisted.html">Unlisted</a>`)
+		case "/linked.html", "/unlisted.html", "/orphan.html":
+			fmt.Fprint(w, "content")
+		case "/sitemap_index.xml":
+			fmt.Fprintf(w, `<sitemapindex><sitemap><loc>%[1]s/pages.xml</loc></sitemap><sitemap><loc>%[1]s/more.xml.gz</loc></sitemap></sitemapindex>`, server.URL)
+		case "/pages.xml":
+			fmt.Fprint(w, `<urlset><url><loc>/index.html</loc></url><url><loc>/linked.html</loc></url></urlset>`)
+		case "/more.xml.gz":
+			w.Header().Set("Content-Type", "application/gzip")
+			w.Write(gzipString(t, `<urlset><url><loc>/orphan.html</loc></url><url><loc>/gone.html</loc></url></urlset>`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	cfg := &Config{Hosts: []HostRule{{DomainGlob: "*", IgnoreRobots: true}}}
+	if err := cfg.compile(); err != nil {
+		t.Fatalf("compile() error = %v", err)
+	}
+	c, err := newCrawler(server.URL+"/", server.URL+"/index.html", cfg)
+	if err != nil {
+		t.Fatalf("newCrawler() error = %v", err)
+	}
+
+	if err := c.loadSitemap(context.Background(), "/sitemap_index.xml"); err != nil {
+		t.Fatalf("loadSitemap() error = %v", err)
+	}
+	if len(c.sitemapPages) != 4 {
+		t.Errorf("loadSitemap() found %d pages, want 4", len(c.sitemapPages))
+	}
+
+	c.crawl(context.Background(), c.homeURL)
+
+	got := c.checkSitemap()
+	want := SitemapReport{
+		Orphans: []string{server.URL + "/orphan.html"},
+		Missing: []string{server.URL + "/unlisted.html"},
+		Dead:    []string{server.URL + "/gone.html"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("checkSitemap() = %+v, want %+v", got, want)
+	}
+
+	// Sitemap entries are reported as dead links found in the sitemap that listed them
+	wantDead := []string{"dead link " + server.URL + "/gone.html found at: " + server.URL + "/more.xml.gz"}
+	if !equalStringSlices(deadLinkStrings(c.deadLinks), wantDead) {
+		t.Errorf("crawl() deadLinks = %v, want %v", c.deadLinks, wantDead)
+	}
+}
+
+func Test_linkGraph(t *testing.T) {
+	g := newLinkGraph()
+	g.addLink("a", "b")
+	g.addLink("a", "b")
+	g.addLink("b", "c")
+	g.addLink("d", "c")
+
+	wantReachable := map[string]bool{"a": true, "b": true, "c": true}
+	if got := g.reachable("a"); !reflect.DeepEqual(got, wantReachable) {
+		t.Errorf("reachable() = %v, want %v", got, wantReachable)
+	}
+
+	wantInbound := map[string]int{"b": 1, "c": 2}
+	if got := g.inbound(); !reflect.DeepEqual(got, wantInbound) {
+		t.Errorf("inbound() = %v, want %v", got, wantInbound)
+	}
+}
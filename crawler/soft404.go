@@ -0,0 +1,226 @@
+package crawler
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Soft404Config holds the settings for spotting error pages served with a 2xx status
+type Soft404Config struct {
+
+	// Fetch a URL that cannot exist from each host and treat pages that look like its answer as dead
+	Probe bool `json:"probe"`
+
+	// Regular expressions that mark a page as an error page when they match its body
+	Patterns []string `json:"patterns"`
+
+	// Compiled form of Patterns
+	patterns []*regexp.Regexp
+}
+
+// Compiles the body patterns
+func (s *Soft404Config) compile() error {
+	s.patterns = nil
+	for _, pattern := range s.Patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return err
+		}
+		s.patterns = append(s.patterns, re)
+	}
+	return nil
+}
+
+// Whether soft-404 detection is turned on at all
+func (s *Soft404Config) enabled() bool {
+	return s.Probe || len(s.patterns) > 0
+}
+
+// What a host's error page looks like
+type errorPageFingerprint struct {
+
+	// Body with the probed path taken out, since error pages often repeat the path they could not find
+	body []byte
+}
+
+// Share of a page that must be the same as the error page, at its start and end, for the page
+// to count as the error page. What is left over is where templates put the details of the request.
+const soft404Similarity = 0.95
+
+// Fingerprints the error page of every host the crawler visits and compares pages against it
+type soft404Detector struct {
+	config *Config
+	client *http.Client
+
+	limiter *hostLimiter
+
+	// Protects hosts, but not the entries in it
+	mu sync.Mutex
+
+	// Error page per scheme and host
+	hosts map[string]*errorPageEntry
+}
+
+// Error page of one host, probed once by whoever needs it first
+type errorPageEntry struct {
+	once sync.Once
+
+	// Nil for hosts that answer missing pages with a real 404
+	errorPage *errorPageFingerprint
+}
+
+// Function to create a new soft-404 detector
+func newSoft404Detector(cfg *Config, client *http.Client, limiter *hostLimiter) *soft404Detector {
+	return &soft404Detector{
+		config:  cfg,
+		client:  client,
+		limiter: limiter,
+		hosts:   make(map[string]*errorPageEntry),
+	}
+}
+
+// Checks whether soft-404 detection is turned on, a nil detector never detects anything
+func (d *soft404Detector) enabled() bool {
+	return d != nil && d.config != nil && d.config.Soft404.enabled()
+}
+
+// Checks whether the body of a page served with a 2xx status is really an error page
+func (d *soft404Detector) isSoft404(ctx context.Context, URL string, body []byte) bool {
+	if !d.enabled() {
+		return false
+	}
+
+	// Configured patterns are the most reliable sign
+	for _, re := range d.config.Soft404.patterns {
+		if re.Match(body) {
+			return true
+		}
+	}
+
+	if !d.config.Soft404.Probe {
+		return false
+	}
+
+	parsedURL, err := url.Parse(URL)
+	if err != nil {
+		return false
+	}
+
+	errorPage := d.lookup(ctx, parsedURL)
+	if errorPage == nil {
+		return false
+	}
+
+	return errorPage.matches(parsedURL.Path, body)
+}
+
+// Returns the error page of the URL's host, probing the host on first use
+func (d *soft404Detector) lookup(ctx context.Context, parsedURL *url.URL) *errorPageFingerprint {
+	key := parsedURL.Scheme + "://" + parsedURL.Host
+
+	// Only hold the detector's lock to find the host's entry, so a slow host does not hold up the others
+	d.mu.Lock()
+	entry, ok := d.hosts[key]
+	if !ok {
+		entry = &errorPageEntry{}
+		d.hosts[key] = entry
+	}
+	d.mu.Unlock()
+
+	// Pages of the same host wait for the first one to probe it
+	entry.once.Do(func() {
+		entry.errorPage = d.probe(ctx, key)
+	})
+	return entry.errorPage
+}
+
+// Function to fetch a random path that cannot exist on a host and fingerprint the answer
+func (d *soft404Detector) probe(ctx context.Context, hostURL string) *errorPageFingerprint {
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		log.Println("Error generating soft-404 probe:", err)
+		return nil
+	}
+	probePath := "/" + robotsUserAgent + "-soft404-probe-" + hex.EncodeToString(token)
+	probeURL := hostURL + probePath
+
+	release := d.limiter.acquire(probeURL)
+	defer release()
+
+	resp, err := fetchHTTPResponse(ctx, d.client, probeURL)
+	if err != nil {
+		log.Println("Error probing for soft 404s:", probeURL, "Error:", err)
+		return nil
+	}
+	defer resp.Body.Close()
+
+	// A host that answers missing pages with an error status has no soft 404s to find
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil
+	}
+
+	body, err := io.ReadAll(newCappedReader(resp.Body, d.config.maxPageSize()))
+	if err != nil {
+		log.Println("Error reading soft-404 probe:", probeURL, "Error:", err)
+		return nil
+	}
+
+	log.Println("Host answers missing pages with status", resp.StatusCode, "comparing its pages with the error page:", hostURL)
+	return &errorPageFingerprint{body: withoutPath(body, probePath)}
+}
+
+// Checks whether a page at pagePath looks like the error page
+func (f *errorPageFingerprint) matches(pagePath string, body []byte) bool {
+	body = withoutPath(body, pagePath)
+
+	// The same page apart from the path it mentions
+	if bytes.Equal(body, f.body) {
+		return true
+	}
+
+	// The same page apart from one stretch in the middle, such as a request ID or a search box
+	// filled in with the path. Pages of a site share their header and footer, but not that much.
+	return sharedEnds(body, f.body) >= soft404Similarity
+}
+
+// Function to measure how much of the longer of two bodies they have in common at their start and end
+func sharedEnds(a, b []byte) float64 {
+	longest := len(a)
+	if len(b) > longest {
+		longest = len(b)
+	}
+	if longest == 0 {
+		return 1
+	}
+
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+
+	// The suffix may not reach into the prefix, or the same bytes would count twice
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	return float64(prefix+suffix) / float64(longest)
+}
+
+// Removes every mention of a path from a body, both as is and escaped
+func withoutPath(body []byte, pagePath string) []byte {
+	if pagePath == "" || pagePath == "/" {
+		return body
+	}
+	body = bytes.ReplaceAll(body, []byte(pagePath), nil)
+	return bytes.ReplaceAll(body, []byte(url.PathEscape(strings.TrimPrefix(pagePath, "/"))), nil)
+}
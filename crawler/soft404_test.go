@@ -0,0 +1,275 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Header and footer every page of the test site shares, the title included
+const (
+	siteHeader = `<html><head><title>KDLP</title><link rel="stylesheet" href="/style.css"></head><body><nav><a href="/index.html">Home</a> <a href="/course.html">Course</a> <a href="/slides.html">Slides</a></nav><main>`
+	siteFooter = `</main><footer>Kernel Development Learning Pipeline, run by the underground software community.</footer></body></html>`
+)
+
+// Error template served with status 200 for every path the site does not know, mentioning the path
+const notFoundTemplate = siteHeader + `<p>Sorry, %s does not exist.</p>` + siteFooter
+
+func Test_soft404Detector_isSoft404(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/index.html":
+			fmt.Fprint(w, `<html><head><title>KDLP</title></head><body><p>Welcome to the course, read the syllabus first.</p></body></html>`)
+		default:
+			fmt.Fprintf(w, notFoundTemplate, r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		soft404 Soft404Config
+		path    string
+		body    string
+		want    bool
+	}{
+		{
+			name:    "Real page",
+			soft404: Soft404Config{Probe: true},
+			path:    "/index.html",
+			body:    `<html><head><title>KDLP</title></head><body><p>Welcome to the course, read the syllabus first.</p></body></html>`,
+			want:    false,
+		},
+		{
+			name:    "Same error page for another path",
+			soft404: Soft404Config{Probe: true},
+			path:    "/missing.html",
+			body:    fmt.Sprintf(notFoundTemplate, "/missing.html"),
+			want:    true,
+		},
+		{
+			name:    "Error page mentioning the path with its query",
+			soft404: Soft404Config{Probe: true},
+			path:    "/old/slides.html",
+			body:    fmt.Sprintf(notFoundTemplate, "/old/slides.html?from=feed"),
+			want:    true,
+		},
+		{
+			name:    "Short page with the site-wide title and the same length",
+			soft404: Soft404Config{Probe: true},
+			path:    "/lab.html",
+			body:    siteHeader + `<p>See the lab sheet for this week.</p>` + siteFooter,
+			want:    false,
+		},
+		{
+			name:    "Same title but much longer",
+			soft404: Soft404Config{Probe: true},
+			path:    "/lecture.html",
+			body:    siteHeader + strings.Repeat("<p>Lecture notes.</p>", 50) + siteFooter,
+			want:    false,
+		},
+		{
+			name:    "Matching pattern",
+			soft404: Soft404Config{Patterns: []string{`(?i)page not found`}},
+			path:    "/index.html",
+			body:    `<h1>Page Not Found</h1>`,
+			want:    true,
+		},
+		{
+			name:    "Detection turned off",
+			soft404: Soft404Config{},
+			path:    "/missing.html",
+			body:    fmt.Sprintf(notFoundTemplate, "/missing.html"),
+			want:    false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{Soft404: tt.soft404, MaxPageSize: defaultMaxPageSize}
+			if err := cfg.compile(); err != nil {
+				t.Fatalf("compile() error = %v", err)
+			}
+			d := newSoft404Detector(cfg, server.Client(), nil)
+
+			if got := d.isSoft404(context.Background(), server.URL+tt.path, []byte(tt.body)); got != tt.want {
+				t.Errorf("isSoft404() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_soft404Detector_realNotFound(t *testing.T) {
+	// A host answering missing pages with a real 404 is probed once and never reports soft 404s
+	probes := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		probes++
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	cfg := &Config{Soft404: Soft404Config{Probe: true}, MaxPageSize: defaultMaxPageSize}
+	d := newSoft404Detector(cfg, server.Client(), nil)
+
+	for _, path := range []string{"/a.html", "/b.html"} {
+		if d.isSoft404(context.Background(), server.URL+path, []byte("404 page not found\n")) {
+			t.Errorf("isSoft404(%s) = true, want false", path)
+		}
+	}
+	if probes != 1 {
+		t.Errorf("host probed %d times, want 1", probes)
+	}
+}
+
+func TestCrawler_crawl_soft404(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/index.html":
+			fmt.Fprint(w, `<html><head><title>KDLP</title></head><body><a href="gone.html">Gone</a></body></html>`)
+		default:
+			fmt.Fprintf(w, notFoundTemplate, r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	cfg := &Config{
+		Hosts:   []HostRule{{DomainGlob: "*", IgnoreRobots: true}},
+		Soft404: Soft404Config{Probe: true},
+	}
+	if err := cfg.compile(); err != nil {
+		t.Fatalf("compile() error = %v", err)
+	}
+	c, err := newCrawler(server.URL+"/", server.URL+"/index.html", cfg)
+	if err != nil {
+		t.Fatalf("newCrawler() error = %v", err)
+	}
+	c.crawl(context.Background(), c.homeURL)
+
+	wantDead := []string{"dead link " + server.URL + "/gone.html found at: " + server.URL + "/index.html"}
+	if !equalStringSlices(deadLinkStrings(c.deadLinks), wantDead) {
+		t.Errorf("crawl() deadLinks = %v, want %v", c.deadLinks, wantDead)
+	}
+}
+
+func TestCrawl_soft404_defaultHosts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt":
+			http.NotFound(w, r)
+		case "/index.html":
+			fmt.Fprint(w, `<html><head><title>KDLP</title></head><body><a href="gone.html">Gone</a></body></html>`)
+		default:
+			fmt.Fprintf(w, notFoundTemplate, r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	// The catch-all rule allows one request at a time, the probe must not wait on the page's own slot
+	cfg := DefaultConfig()
+	for i := range cfg.Hosts {
+		cfg.Hosts[i].Delay = Duration{}
+	}
+	cfg.Soft404.Probe = true
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	done := make(chan *Result, 1)
+	go func() {
+		result, _ := Crawl(ctx, Options{Domain: server.URL + "/", HomeURL: server.URL + "/index.html", Config: cfg})
+		done <- result
+	}()
+
+	select {
+	case result := <-done:
+		wantDead := []string{"dead link " + server.URL + "/gone.html found at: " + server.URL + "/index.html"}
+		if result == nil || !equalStringSlices(deadLinkStrings(result.DeadLinks), wantDead) {
+			t.Errorf("Crawl() = %+v, want dead links %v", result, wantDead)
+		}
+	case <-time.After(15 * time.Second):
+		t.Fatal("Crawl() with soft-404 probes and the default host rules did not return")
+	}
+}
+
+func Test_sharedEnds(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want float64
+	}{
+		{name: "Same", a: "abcd", b: "abcd", want: 1},
+		{name: "Both empty", a: "", b: "", want: 1},
+		{name: "Different middle", a: "abXXcd", b: "abYYcd", want: 4.0 / 6},
+		{name: "Inserted middle", a: "abcd", b: "abXXXXcd", want: 4.0 / 8},
+		{name: "Repeated bytes count once", a: "aaaa", b: "aa", want: 2.0 / 4},
+		{name: "Nothing shared", a: "abcd", b: "wxyz", want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sharedEnds([]byte(tt.a), []byte(tt.b)); got != tt.want {
+				t.Errorf("sharedEnds(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_soft404Detector_slowHost(t *testing.T) {
+	// The slow host answers probes once the test lets it, and counts how often it is probed
+	var slowProbes int32
+	unblock := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&slowProbes, 1)
+		<-unblock
+		http.NotFound(w, r)
+	}))
+	defer slow.Close()
+	defer close(unblock)
+
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, notFoundTemplate, r.URL.Path)
+	}))
+	defer fast.Close()
+
+	cfg := &Config{Soft404: Soft404Config{Probe: true}, MaxPageSize: defaultMaxPageSize}
+	d := newSoft404Detector(cfg, http.DefaultClient, nil)
+
+	// Many pages of the slow host wait for its probe at once
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d.isSoft404(context.Background(), slow.URL+"/index.html", []byte("<html></html>"))
+		}()
+	}
+
+	// Wait until the slow host is being asked
+	for atomic.LoadInt32(&slowProbes) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// Meanwhile pages of the fast host are compared with its error page right away
+	done := make(chan bool)
+	go func() {
+		done <- d.isSoft404(context.Background(), fast.URL+"/missing.html", []byte(fmt.Sprintf(notFoundTemplate, "/missing.html")))
+	}()
+	select {
+	case got := <-done:
+		if !got {
+			t.Errorf("isSoft404() = false, want true")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("isSoft404() for a fast host waited on a slow host's probe")
+	}
+
+	unblock <- struct{}{}
+	wg.Wait()
+	if got := atomic.LoadInt32(&slowProbes); got != 1 {
+		t.Errorf("slow host probed %d times, want 1", got)
+	}
+}
@@ -0,0 +1,65 @@
+package crawler
+
+import (
+	"log"
+	"net/http"
+)
+
+// State of one crawl
+type crawler struct {
+
+	// Stores base domain of website being crawled
+	domain string
+
+	// Starting URL for crawler
+	homeURL string
+
+	// Map to track visited URLs, keyed on their canonical form
+	visited map[string]bool
+
+	// Number of URLs checked, skipped URLs are visited but not checked
+	checked int
+
+	// Slice to store dead links
+	deadLinks []DeadLink
+
+	// Crawler settings
+	config *Config
+
+	// HTTP client used for every request
+	client *http.Client
+
+	// Per-host rate limiting for outgoing requests
+	limiter *hostLimiter
+
+	// Decides which URLs are left alone, robots.txt included
+	filter *urlFilter
+
+	// Spots error pages served with a 2xx status
+	soft404 *soft404Detector
+
+	// Limits on depth, pages, time and bytes of the crawl
+	budget *crawlBudget
+
+	// URLs waiting to be crawled
+	frontier frontier
+
+	// Links between pages and the status of every URL checked
+	graph *linkGraph
+
+	// Pages listed in the sitemap with the sitemap that listed them, nil without a sitemap
+	sitemapPages map[string]string
+
+	// Told about every page, link and finding of the crawl
+	observers *observerList
+
+	// Where lines about the crawl's progress go, nowhere if nil
+	progress *log.Logger
+}
+
+// Writes a line about the crawl's progress, if anyone wants it
+func (c *crawler) logProgress(v ...interface{}) {
+	if c.progress != nil {
+		c.progress.Println(v...)
+	}
+}
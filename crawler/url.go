@@ -0,0 +1,159 @@
+package crawler
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Checks whether a given URL is valid
+func isValidURL(URL string) bool {
+	// Parse the URL
+	parsedURL, err := url.Parse(URL)
+	if err != nil {
+		return false
+	}
+
+	// Check if the URL has a scheme (e.g., http, https, etc.)
+	if parsedURL.Scheme == "" {
+		return false
+	}
+
+	// Check if the URL has a host
+	if parsedURL.Host == "" {
+		return false
+	}
+
+	return true
+}
+
+// Checks whether a URL is internal and doesn't contain "cgit" after the domain name
+func isInternalURL(URL string, domain string) bool {
+	return strings.HasPrefix(URL, domain) && !strings.Contains(URL, domain+"cgit")
+}
+
+// Function to send a request without a body, using the default client if client is nil.
+// The request is cancelled when ctx is done.
+func sendRequest(ctx context.Context, client *http.Client, method, URL string) (*http.Response, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, URL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.Do(req)
+}
+
+// Function to fetch HTTP response
+func fetchHTTPResponse(ctx context.Context, client *http.Client, URL string) (*http.Response, error) {
+	resp, err := sendRequest(ctx, client, http.MethodGet, URL)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// Function to check URL's HTTP status code with a HEAD request, falling back to GET.
+// Also tells whether the GET was needed.
+func headURLStatus(ctx context.Context, client *http.Client, URL string) (int, bool, error) {
+	resp, err := sendRequest(ctx, client, http.MethodHead, URL)
+	if err != nil {
+		return 0, false, err
+	}
+	resp.Body.Close()
+
+	// Some servers do not implement HEAD, ask them again with GET
+	if resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented {
+		statusCode, err := getURLStatus(ctx, client, URL)
+		return statusCode, true, err
+	}
+
+	return resp.StatusCode, false, nil
+}
+
+// Function to check URL's HTTP status code with a GET request, discarding the body
+func getURLStatus(ctx context.Context, client *http.Client, URL string) (int, error) {
+	resp, err := fetchHTTPResponse(ctx, client, URL)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+
+	return resp.StatusCode, nil
+}
+
+// Reader that stops after a maximum number of bytes and counts how many it read
+type cappedReader struct {
+	r io.Reader
+
+	// Bytes that may still be read, negative for no limit
+	remaining int64
+
+	// Bytes read so far
+	n int64
+
+	// Whether the underlying reader had more data than the limit allowed
+	truncated bool
+}
+
+// Function to cap a reader at maxSize bytes, 0 means no limit
+func newCappedReader(r io.Reader, maxSize int64) *cappedReader {
+	if maxSize <= 0 {
+		maxSize = -1
+	}
+	return &cappedReader{r: r, remaining: maxSize}
+}
+
+func (c *cappedReader) Read(p []byte) (int, error) {
+	if c.remaining == 0 {
+		// Peek one byte to tell a body of exactly the limit from a longer one
+		if n, _ := c.r.Read(make([]byte, 1)); n > 0 {
+			c.truncated = true
+		}
+		return 0, io.EOF
+	}
+
+	if c.remaining > 0 && int64(len(p)) > c.remaining {
+		p = p[:c.remaining]
+	}
+
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	if c.remaining > 0 {
+		c.remaining -= int64(n)
+	}
+
+	return n, err
+}
+
+// Checks whether a URL looks like it points to a page with links, such as HTML or Markdown, rather than a file such as a PDF or tarball
+func isPageURL(URL string) bool {
+	ext := urlExtension(URL)
+	return ext == "" || extractorsByExtension[ext] != nil
+}
+
+// Function to resolve URLs
+func resolveURL(baseURL string, u string) (string, error) {
+	relURL, err := url.Parse(u)
+	if err != nil {
+		return "", err
+	}
+
+	baseURLParsed, err := url.Parse(baseURL)
+	if err != nil {
+		return "", err
+	}
+
+	absURL := baseURLParsed.ResolveReference(relURL)
+	return absURL.String(), nil
+}
+
+// Checks if the URL is a fake example URL to be skipped
+func isFakeURL(URL string) bool {
+	return strings.Contains(URL, "your.computers.ip.addr")
+}
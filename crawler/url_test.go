@@ -1,8 +1,10 @@
-package main
+package crawler
 
 import (
+	"context"
 	"io"
 	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 )
@@ -84,7 +86,7 @@ func Test_isInternalURL(t *testing.T) {
 	}
 }
 
-func Test_checkURLStatus(t *testing.T) {
+func Test_headURLStatus(t *testing.T) {
 	type args struct {
 		URL string
 	}
@@ -140,146 +142,174 @@ func Test_checkURLStatus(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := checkURLStatus(tt.args.URL)
+			got, _, err := headURLStatus(context.Background(), http.DefaultClient, tt.args.URL)
 			if (err != nil) != tt.wantErr {
-				t.Errorf("checkURLStatus() error = %v, wantErr %v", err, tt.wantErr)
+				t.Errorf("headURLStatus() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
 			if got != tt.status {
-				t.Errorf("checkURLStatus() = %v, want %v", got, tt.status)
+				t.Errorf("headURLStatus() = %v, want %v", got, tt.status)
 			}
 		})
 	}
 }
 
-func Test_retrieveHTTPContent(t *testing.T) {
+func Test_fetchHTTPResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<html></html>"))
+	}))
+	defer server.Close()
+
 	type args struct {
 		URL string
 	}
 	tests := []struct {
 		name    string
 		args    args
-		content string
 		wantErr bool
 	}{
 		{
-			name:    "Succesful fetch",
-			args:    args{URL: "https://www.google.com"},
-			content: "not empty", // Expecting a non-empty response
+			name: "Successful fetch response",
+			args: args{
+				URL: server.URL,
+			},
 			wantErr: false,
 		},
 		{
-			name:    "Unsuccesful fetch",
-			args:    args{URL: "Invalid"},
-			content: "", // Expecting an empty response
-			wantErr: true,
-		},
-
-		{
-			name:    "Unsuccessful fetch - Non-existent resource",
-			args:    args{URL: "https://www.example.com/non-existent-page"},
-			content: "", // Expecting an empty response
+			name: "Unsuccessful fetch response",
+			args: args{
+				URL: "Invalid",
+			},
 			wantErr: true,
 		},
 	}
+
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := retrieveHTTPContent(tt.args.URL)
+			got, err := fetchHTTPResponse(context.Background(), http.DefaultClient, tt.args.URL)
 			if (err != nil) != tt.wantErr {
-				t.Errorf("fetchHTTPContent() error = %v, wantErr %v", err, tt.wantErr)
+				t.Errorf("fetchHTTPResponse() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
-			if tt.content != "" && got == "" {
-				t.Errorf("fetchHTTPContent() returned an empty response, but a non-empty response was expected")
+			if tt.wantErr {
+				return
 			}
-			if tt.content == "" && got != "" {
-				t.Errorf("fetchHTTPContent() returned a non-empty response, but an empty response was expected")
+			defer got.Body.Close()
+			if got.StatusCode != http.StatusOK {
+				t.Errorf("fetchHTTPResponse() status = %v, want %v", got.StatusCode, http.StatusOK)
 			}
 		})
 	}
 }
 
-func Test_readHTTPResponseBody(t *testing.T) {
-	type args struct {
-		resp *http.Response
-	}
+func Test_cappedReader(t *testing.T) {
 	tests := []struct {
-		name    string
-		args    args
-		want    string
-		wantErr bool
+		name          string
+		content       string
+		maxSize       int64
+		want          string
+		wantTruncated bool
 	}{
-		{
-			name: "Read non-empty response body",
-			args: args{
-				resp: &http.Response{
-					Body: io.NopCloser(strings.NewReader("I'm not empty!")),
-				},
-			},
-			want: "I'm not empty!",
-		},
-		{
-			name: "Read empty response body",
-			args: args{
-				resp: &http.Response{
-					Body: io.NopCloser(strings.NewReader("")),
-				},
-			},
-			want: "",
-		},
+		{name: "Smaller than the cap", content: "0123456789", maxSize: 20, want: "0123456789"},
+		{name: "Exactly the cap", content: "0123456789", maxSize: 10, want: "0123456789"},
+		{name: "Larger than the cap", content: "0123456789", maxSize: 4, want: "0123", wantTruncated: true},
+		{name: "No cap", content: "0123456789", maxSize: 0, want: "0123456789"},
 	}
-
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := readHTTPResponseBody(tt.args.resp)
-			if (err != nil) != tt.wantErr {
-				t.Errorf("readHTTPResponseBody() error = %v, wantErr %v", err, tt.wantErr)
-				return
+			r := newCappedReader(strings.NewReader(tt.content), tt.maxSize)
+			got, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatalf("ReadAll() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("read %q, want %q", got, tt.want)
 			}
-			if got != tt.want {
-				t.Errorf("readHTTPResponseBody() = %v, want %v", got, tt.want)
+			if r.n != int64(len(tt.want)) {
+				t.Errorf("counted %d bytes, want %d", r.n, len(tt.want))
+			}
+			if r.truncated != tt.wantTruncated {
+				t.Errorf("truncated = %v, want %v", r.truncated, tt.wantTruncated)
 			}
 		})
 	}
 }
 
-func Test_fetchHTTPResponse(t *testing.T) {
-	type args struct {
-		URL string
-	}
+func Test_headURLStatus_fallback(t *testing.T) {
 	tests := []struct {
-		name    string
-		args    args
-		want    *http.Response
-		wantErr bool
+		name       string
+		headStatus int
+		status     int
+		wantGets   int
 	}{
 		{
-			name: "Successful fetch response",
-			args: args{
-				URL: "https://www.google.com",
-			},
-			wantErr: false,
+			name:       "HEAD supported",
+			headStatus: http.StatusOK,
+			status:     http.StatusOK,
+			wantGets:   0,
 		},
 		{
-			name: "Unsuccessful fetch response",
-			args: args{
-				URL: "Invalid",
-			},
-			wantErr: true,
+			name:       "HEAD not allowed",
+			headStatus: http.StatusMethodNotAllowed,
+			status:     http.StatusOK,
+			wantGets:   1,
+		},
+		{
+			name:       "HEAD not implemented",
+			headStatus: http.StatusNotImplemented,
+			status:     http.StatusNotFound,
+			wantGets:   1,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := fetchHTTPResponse(tt.args.URL)
-			if (err != nil) != tt.wantErr {
-				t.Errorf("fetchHTTPResponse() error = %v, wantErr %v", err, tt.wantErr)
-				return
+			gets := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodHead {
+					w.WriteHeader(tt.headStatus)
+					return
+				}
+				gets++
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			got, retried, err := headURLStatus(context.Background(), http.DefaultClient, server.URL)
+			if err != nil {
+				t.Fatalf("headURLStatus() error = %v", err)
 			}
-			if tt.wantErr {
-				return
+			if got != tt.status {
+				t.Errorf("headURLStatus() = %v, want %v", got, tt.status)
+			}
+			if gets != tt.wantGets {
+				t.Errorf("headURLStatus() sent %d GET requests, want %d", gets, tt.wantGets)
+			}
+			if retried != (tt.wantGets > 0) {
+				t.Errorf("headURLStatus() retried = %v, want %v", retried, tt.wantGets > 0)
+			}
+		})
+	}
+}
+
+func Test_isPageURL(t *testing.T) {
+	tests := []struct {
+		name string
+		URL  string
+		want bool
+	}{
+		{name: "Directory", URL: "https://kdlp.underground.software/course/", want: true},
+		{name: "HTML page", URL: "https://kdlp.underground.software/index.html", want: true},
+		{name: "Markdown page", URL: "https://kdlp.underground.software/index.md", want: true},
+		{name: "Stylesheet", URL: "https://kdlp.underground.software/style.css", want: true},
+		{name: "PDF", URL: "https://kdlp.underground.software/slides.pdf", want: false},
+		{name: "Tarball", URL: "https://kdlp.underground.software/linux.tar.gz", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isPageURL(tt.URL); got != tt.want {
+				t.Errorf("isPageURL(%q) = %v, want %v", tt.URL, got, tt.want)
 			}
-			_ = got
 		})
 	}
 }
@@ -3,7 +3,9 @@ module github.com/underground-software/KDLP_Webcrawler.git
 go 1.20
 
 require (
+	github.com/gobwas/glob v0.2.3
 	github.com/gocolly/colly/v2 v2.1.0
+	github.com/temoto/robotstxt v1.1.2
 	golang.org/x/net v0.14.0
 )
 
@@ -13,12 +15,10 @@ require (
 	github.com/antchfx/htmlquery v1.3.0 // indirect
 	github.com/antchfx/xmlquery v1.3.17 // indirect
 	github.com/antchfx/xpath v1.2.4 // indirect
-	github.com/gobwas/glob v0.2.3 // indirect
 	github.com/golang/groupcache v0.0.0-20210331224755-41bb18bfe9da // indirect
 	github.com/golang/protobuf v1.5.3 // indirect
 	github.com/kennygrant/sanitize v1.2.4 // indirect
 	github.com/saintfish/chardet v0.0.0-20230101081208-5e3ef4b5456d // indirect
-	github.com/temoto/robotstxt v1.1.2 // indirect
 	golang.org/x/text v0.12.0 // indirect
 	google.golang.org/appengine v1.6.7 // indirect
 	google.golang.org/protobuf v1.31.0 // indirect
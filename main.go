@@ -7,8 +7,19 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
+	"log"
+	"net/http"
 	"os"
+	"os/signal"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/underground-software/KDLP_Webcrawler.git/crawler"
+	"github.com/underground-software/KDLP_Webcrawler.git/server"
 )
 
 func help() {
@@ -19,14 +30,295 @@ func help() {
 	fmt.Println("\nOptions:")
 	fmt.Println("\t-h, --help             shows a manual")
 	fmt.Println("\t--crawl                recursively crawls domain and retrieves dead links with reference URLS")
-	fmt.Println("\t--crawl-colly          recursively crawls domain and retrieves dead links with reference URLS via colly")
+	fmt.Println("\t--crawl-colly          same as --crawl --engine colly")
+	fmt.Println("\tserve                  runs crawls in the background, started and inspected over a REST API")
+	fmt.Println("\tdaemon                 crawls on a schedule and notifies when the broken links change")
+
+	fmt.Println("\nCrawl options:")
+	fmt.Println("\t--config <file>        load settings such as per-host rate limits from a JSON file")
+	fmt.Println("\t--engine <name>        crawl with the custom (default) or the faster colly engine")
+	fmt.Println("\t--webhook-url <url>    post the dead links as JSON to a webhook")
+	fmt.Println("\t--webhook-template <f> text/template file for the text of webhook posts, for chat systems")
+	fmt.Println("\t--smtp-to <addresses>  mail a digest of the dead links to these comma separated addresses")
+	fmt.Println("\t--smtp-addr <address>  SMTP server to send the digest through, localhost:25 by default")
+	fmt.Println("\t--smtp-from <address>  sender of the digest")
+	fmt.Println("\t--smtp-user <name>     user to authenticate as, password in WEBCRAWLER_SMTP_PASSWORD")
+
+	fmt.Println("\nServe options:")
+	fmt.Println("\t--addr <address>       address to listen on, localhost:8080 by default")
+	fmt.Println("\t--profiles <dir>       directory of config files, profile nightly is nightly.json")
+	fmt.Println("\t--runs <dir>           directory to keep runs and their reports in, runs by default")
+	fmt.Println("\t--engine <name>        engine for runs that do not name one")
+
+	fmt.Println("\nDaemon options:")
+	fmt.Println("\t--interval <duration>  time between two crawls, 24h by default")
+	fmt.Println("\t--profile <name>       profile every crawl uses")
+	fmt.Println("\t--notify-command <cmd> shell command run when the broken links change")
+	fmt.Println("\t--metrics-addr <addr>  address to serve Prometheus metrics on, none by default")
+	fmt.Println("\t--webhook-url and --webhook-template post new broken links as for crawls")
+	fmt.Println("\t--smtp-to and the other --smtp options mail a digest when the broken links change")
+	fmt.Println("\t--profiles, --runs and --engine work as for serve")
+
+	fmt.Println("\nExit codes:")
+	fmt.Println("\t0                      nothing wrong was found")
+	fmt.Println("\t1                      dead links or other problems were found")
+	fmt.Println("\t2                      the crawl could not be run or was interrupted")
+
+}
+
+// Adds the webhook flags to flags. The returned function builds the webhook notifier they describe
+// once flags are parsed, nil without --webhook-url. The signing key comes from WEBCRAWLER_WEBHOOK_SECRET
+// rather than a flag, so it does not show in the process list.
+func webhookFlags(flags *flag.FlagSet) func() *server.WebhookNotifier {
+	webhookURL := flags.String("webhook-url", "", "URL to post new broken links to as JSON")
+	templatePath := flags.String("webhook-template", "", "text/template file rendering the text field of webhook posts")
+
+	return func() *server.WebhookNotifier {
+		if *webhookURL == "" {
+			return nil
+		}
+
+		webhook := &server.WebhookNotifier{URL: *webhookURL, Secret: os.Getenv("WEBCRAWLER_WEBHOOK_SECRET"), Retries: 3}
+		if *templatePath != "" {
+			tmpl, err := template.ParseFiles(*templatePath)
+			if err != nil {
+				log.Fatal("Failed to load webhook template: ", err)
+			}
+			webhook.Template = tmpl
+		}
+		return webhook
+	}
+}
+
+// Adds the mail flags to flags. The returned function builds the email notifier they describe once
+// flags are parsed, nil without --smtp-to. The password comes from WEBCRAWLER_SMTP_PASSWORD.
+func emailFlags(flags *flag.FlagSet) func() *server.EmailNotifier {
+	addr := flags.String("smtp-addr", "localhost:25", "SMTP server to send the digest through, as host:port")
+	from := flags.String("smtp-from", "webcrawler@localhost", "sender of the digest")
+	to := flags.String("smtp-to", "", "comma separated recipients of the digest of broken links")
+	username := flags.String("smtp-user", "", "user to authenticate to the SMTP server as, none if empty")
+
+	return func() *server.EmailNotifier {
+		if *to == "" {
+			return nil
+		}
+
+		email := &server.EmailNotifier{Addr: *addr, From: *from, Username: *username, Password: os.Getenv("WEBCRAWLER_SMTP_PASSWORD")}
+		for _, recipient := range strings.Split(*to, ",") {
+			if recipient = strings.TrimSpace(recipient); recipient != "" {
+				email.To = append(email.To, recipient)
+			}
+		}
+		return email
+	}
+}
+
+// Loads the config file given with --config, or the defaults if there is none, the engine picked with --engine
+// and the reporters the crawl's result goes to: the text files, then the webhook and the mail if there are any
+func loadOptions(args []string, defaultEngine string) (*crawler.Config, crawler.Engine, []crawler.Reporter) {
+	flags := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	configPath := flags.String("config", "", "path to a JSON config file")
+	engineName := flags.String("engine", defaultEngine, "crawl engine to use: custom or colly")
+	webhook := webhookFlags(flags)
+	email := emailFlags(flags)
+	flags.Parse(args)
+
+	engine, err := crawler.NewEngine(*engineName)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	reporters := []crawler.Reporter{crawler.TextReporter{}}
+	if w := webhook(); w != nil {
+		reporters = append(reporters, w)
+	}
+	if e := email(); e != nil {
+		reporters = append(reporters, e)
+	}
+
+	if *configPath == "" {
+		return crawler.DefaultConfig(), engine, reporters
+	}
+
+	cfg, err := crawler.LoadConfig(*configPath)
+	if err != nil {
+		log.Fatal("Failed to load config: ", err)
+	}
+
+	return cfg, engine, reporters
+}
+
+// Runs a crawl with the options in args and writes what it finds to text files in the current directory.
+// Returns the exit code for what the crawl found.
+func runCrawl(ctx context.Context, domain, homeURL string, args []string, defaultEngine string) int {
+	cfg, engine, reporters := loadOptions(args, defaultEngine)
+
+	// Rewrite the dead links file as each dead link is found, so an interrupted crawl still leaves it behind
+	result, err := engine.Crawl(ctx, crawler.Options{
+		Domain:    domain,
+		HomeURL:   homeURL,
+		Config:    cfg,
+		Observers: []crawler.Observer{&crawler.DeadLinkFile{Path: "dead_links.txt"}},
+
+		// Show every URL as it is crawled, like the command line always did
+		Progress: log.New(os.Stdout, "", 0),
+	})
+	if result == nil {
+		log.Println("Error crawling:", err)
+		return crawler.ExitCode(result, err)
+	}
+	if err != nil {
+		fmt.Println("Crawl interrupted:", err)
+	}
+
+	// A failing reporter does not keep the others from reporting
+	exitCode := crawler.ExitCode(result, err)
+	for _, reporter := range reporters {
+		if err := reporter.Report(result); err != nil {
+			log.Println("Error reporting crawl:", err)
+			exitCode = crawler.ExitError
+		}
+	}
 
+	return exitCode
+}
+
+// Serves the REST API for starting and inspecting crawls until the context is done
+func runServe(ctx context.Context, domain, homeURL string, args []string) int {
+	flags := flag.NewFlagSet(os.Args[0]+" serve", flag.ExitOnError)
+	addr := flags.String("addr", "localhost:8080", "address to listen on, the API has no authentication")
+	profilesDir := flags.String("profiles", "", "directory of JSON config files, one per profile")
+	runsDir := flags.String("runs", "runs", "directory to keep runs and their reports in")
+	engineName := flags.String("engine", "custom", "crawl engine runs use when they do not name one")
+	flags.Parse(args)
+
+	if _, err := crawler.NewEngine(*engineName); err != nil {
+		log.Fatal(err)
+	}
+
+	store, err := server.OpenStore(*runsDir)
+	if err != nil {
+		log.Fatal("Failed to open run store: ", err)
+	}
+
+	runner := &server.Runner{
+		Store:         store,
+		Profiles:      server.Profiles{Dir: *profilesDir},
+		Domain:        domain,
+		HomeURL:       homeURL,
+		DefaultEngine: *engineName,
+		Metrics:       server.NewMetrics(),
+	}
+	srv := &http.Server{Addr: *addr, Handler: server.NewHandler(runner)}
+
+	// Stop taking requests on Ctrl-C, then cancel the runs in progress and wait for them to save their reports
+	go func() {
+		<-ctx.Done()
+		srv.Shutdown(context.Background())
+	}()
+
+	fmt.Println("Serving the crawl API on", *addr)
+	err = srv.ListenAndServe()
+	runner.Shutdown()
+	if err != http.ErrServerClosed {
+		log.Println("Error serving:", err)
+		return crawler.ExitError
+	}
+
+	return crawler.ExitOK
+}
+
+// Crawls on a schedule until the context is done, notifying when the broken links change
+func runDaemon(ctx context.Context, domain, homeURL string, args []string) int {
+	flags := flag.NewFlagSet(os.Args[0]+" daemon", flag.ExitOnError)
+	interval := flags.Duration("interval", 24*time.Hour, "time between two crawls, such as 6h or 30m")
+	profile := flags.String("profile", server.DefaultProfile, "profile every crawl uses")
+	profilesDir := flags.String("profiles", "", "directory of JSON config files, one per profile")
+	runsDir := flags.String("runs", "runs", "directory to keep runs and their reports in")
+	engineName := flags.String("engine", "custom", "crawl engine to use: custom or colly")
+	notifyCommand := flags.String("notify-command", "", "shell command run when the broken links change, given the change as JSON")
+	webhook := webhookFlags(flags)
+	email := emailFlags(flags)
+	metricsAddr := flags.String("metrics-addr", "", "address to serve Prometheus metrics on, such as :9090, none if empty")
+	flags.Parse(args)
+
+	// Fail right away rather than at every scheduled crawl
+	if _, err := crawler.NewEngine(*engineName); err != nil {
+		log.Fatal(err)
+	}
+	if _, err := (server.Profiles{Dir: *profilesDir}).Load(*profile); err != nil {
+		log.Fatal("Failed to load profile: ", err)
+	}
+
+	store, err := server.OpenStore(*runsDir)
+	if err != nil {
+		log.Fatal("Failed to open run store: ", err)
+	}
+
+	daemon := &server.Daemon{
+		Runner: &server.Runner{
+			Store:    store,
+			Profiles: server.Profiles{Dir: *profilesDir},
+			Domain:   domain,
+			HomeURL:  homeURL,
+		},
+		Profile:  *profile,
+		Engine:   *engineName,
+		Interval: *interval,
+	}
+	if *notifyCommand != "" {
+		daemon.Notifiers = append(daemon.Notifiers, server.CommandNotifier{Command: []string{"sh", "-c", *notifyCommand}})
+	}
+	if w := webhook(); w != nil {
+		daemon.Notifiers = append(daemon.Notifiers, w)
+	}
+	if e := email(); e != nil {
+		daemon.Notifiers = append(daemon.Notifiers, e)
+	}
+
+	// Serve the metrics next to the crawls, the daemon has no API of its own
+	if *metricsAddr != "" {
+		daemon.Runner.Metrics = server.NewMetrics()
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", daemon.Runner.Metrics)
+		srv := &http.Server{Addr: *metricsAddr, Handler: mux}
+		go func() {
+			<-ctx.Done()
+			srv.Shutdown(context.Background())
+		}()
+		go func() {
+			if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+				log.Println("Error serving metrics:", err)
+			}
+		}()
+		fmt.Println("Serving metrics on", *metricsAddr+"/metrics")
+	}
+
+	fmt.Println("Crawling every", *interval, "with profile", *profile)
+	if err := daemon.Run(ctx); err != nil {
+		log.Println("Error running daemon:", err)
+		return crawler.ExitError
+	}
+
+	return crawler.ExitOK
 }
 
 func main() {
 
 	initializeErrorLogging()
 
+	// Stop crawling on Ctrl-C, keeping what was found so far
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+
+	// Set domain and starting URL for crawling
+	domain := "https://prod-01.kdlp.underground.software/"
+	homeURL := domain + "index.md"
+
+	// Public version
+	// domain := "https://kdlp.underground.software/"
+	// homeURL := domain + "index.html"
+
+	exitCode := crawler.ExitOK
 	switch os.Args[1] {
 
 	case "-h":
@@ -35,27 +327,30 @@ func main() {
 	case "--help":
 		help()
 
-		// Custom crawler which returns dead links along with their referring URL
+		// Crawl the site for dead links along with their referring URL
 	case "--crawl":
-		// Set domain and starting URL for crawling
-		domain := "https://prod-01.kdlp.underground.software/"
-		homeURL := domain + "index.md"
+		exitCode = runCrawl(ctx, domain, homeURL, os.Args[2:], "custom")
 
-		// Public version
-		// domain := "https://kdlp.underground.software/"
-		// homeURL := domain + "index.html"
+		// Same as --crawl --engine colly, kept for scripts that use it
+	case "--crawl-colly":
+		exitCode = runCrawl(ctx, domain, homeURL, os.Args[2:], "colly")
 
-		// Call the crawl process
-		runCustomCrawl(domain, homeURL)
+		// Run crawls in the background, started and inspected over HTTP
+	case "serve":
+		exitCode = runServe(ctx, domain, homeURL, os.Args[2:])
 
-		// Colly crawler - Faster than custom crawler, but only returns dead links. Does not return referring URL.
-	case "--crawl-colly":
-		baseURL := "prod-01.kdlp.underground.software"
-		StartCollyCrawl(baseURL)
+		// Crawl on a schedule, keeping every run
+	case "daemon":
+		exitCode = runDaemon(ctx, domain, homeURL, os.Args[2:])
 
 	default:
 
 		fmt.Println(os.Args[1] + " is not a valid argument.\nRunning " + os.Args[0] + " --help may help you!")
+		exitCode = crawler.ExitError
 
 	}
+
+	// os.Exit skips deferred calls, so stop listening for Ctrl-C first
+	stop()
+	os.Exit(exitCode)
 }
@@ -0,0 +1,109 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/underground-software/KDLP_Webcrawler.git/crawler"
+)
+
+// Daemon crawls the site every Interval and tells its notifiers when the broken links change
+type Daemon struct {
+	Runner *Runner
+
+	// Profile and engine of every run, the runner's defaults if empty
+	Profile string
+	Engine  string
+
+	// Time between the start of two runs. A run that takes longer delays the next one.
+	Interval time.Duration
+
+	// Told when a run finds broken links the previous one did not, or no longer finds some it did
+	Notifiers []Notifier
+}
+
+// Run crawls right away and then every interval until ctx is done
+func (d *Daemon) Run(ctx context.Context) error {
+	if d.Interval <= 0 {
+		return fmt.Errorf("invalid crawl interval %v", d.Interval)
+	}
+
+	ticker := time.NewTicker(d.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := d.RunOnce(ctx); err != nil {
+			log.Println("Error running scheduled crawl:", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce crawls once and notifies if the broken links changed since the last finished run
+func (d *Daemon) RunOnce(ctx context.Context) error {
+	profile := d.Profile
+	if profile == "" {
+		profile = DefaultProfile
+	}
+
+	// Look up the previous run first, the new one is in the store as soon as it starts
+	previous, err := d.lastFinished(profile)
+	if err != nil {
+		return err
+	}
+
+	run, result, err := d.Runner.Run(ctx, profile, d.Engine)
+	if err != nil {
+		return err
+	}
+
+	// Only a complete crawl says which links are fixed, and it is the baseline of the next one
+	if run.Status != StatusFinished || result == nil {
+		return nil
+	}
+
+	change := Change{Run: run, Previous: previous, DeadLinks: result.DeadLinks}
+	var before []crawler.DeadLink
+	if previous != nil {
+		if before, err = d.Runner.Store.DeadLinks(previous.ID); err != nil {
+			return err
+		}
+	}
+	change.New, change.Fixed = diffDeadLinks(before, result.DeadLinks)
+
+	if len(change.New) == 0 && len(change.Fixed) == 0 {
+		fmt.Println("Broken links unchanged since the last run,", len(result.DeadLinks), "in total")
+		return nil
+	}
+	fmt.Println("Broken links changed:", len(change.New), "new,", len(change.Fixed), "fixed")
+
+	// A failing notifier does not keep the others from being told
+	for _, notifier := range d.Notifiers {
+		if err := notifier.Notify(ctx, change); err != nil {
+			log.Println("Error sending notification:", err)
+		}
+	}
+	return nil
+}
+
+// Finds the most recent finished run of the profile, nil if there is none
+func (d *Daemon) lastFinished(profile string) (*Run, error) {
+	runs, err := d.Runner.Store.List()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, run := range runs {
+		if run.Status == StatusFinished && run.Profile == profile {
+			return &run, nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,188 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/underground-software/KDLP_Webcrawler.git/crawler"
+)
+
+// Notifier that records the changes it is told about
+type recordingNotifier struct {
+	changes []Change
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, change Change) error {
+	n.changes = append(n.changes, change)
+	return nil
+}
+
+func TestDaemon_RunOnce(t *testing.T) {
+	// The home page links to whichever page is currently missing
+	var mu sync.Mutex
+	missing := "/old.html"
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.URL.Path == "/index.html" {
+			fmt.Fprintf(w, `<a href="%s">Missing</a>`, missing)
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer site.Close()
+
+	notifier := &recordingNotifier{}
+	daemon := &Daemon{Runner: newTestRunner(t, site.URL), Profile: "fast", Notifiers: []Notifier{notifier}}
+
+	// The first run finds a broken link, the second the same one, the third another one
+	for _, path := range []string{"/old.html", "/old.html", "/new.html"} {
+		mu.Lock()
+		missing = path
+		mu.Unlock()
+		if err := daemon.RunOnce(context.Background()); err != nil {
+			t.Fatalf("RunOnce() error = %v", err)
+		}
+	}
+
+	oldLink := crawler.DeadLink{URL: site.URL + "/old.html", ReferringURL: site.URL + "/index.html", StatusCode: 404}
+	newLink := crawler.DeadLink{URL: site.URL + "/new.html", ReferringURL: site.URL + "/index.html", StatusCode: 404}
+
+	if len(notifier.changes) != 2 {
+		t.Fatalf("RunOnce() notified %d times, want 2", len(notifier.changes))
+	}
+	first, second := notifier.changes[0], notifier.changes[1]
+	if first.Previous != nil || !reflect.DeepEqual(first.New, []crawler.DeadLink{oldLink}) || len(first.Fixed) != 0 {
+		t.Errorf("first change = %+v, want %v new", first, oldLink)
+	}
+	if second.Previous == nil || !reflect.DeepEqual(second.New, []crawler.DeadLink{newLink}) || !reflect.DeepEqual(second.Fixed, []crawler.DeadLink{oldLink}) {
+		t.Errorf("second change = %+v, want %v new and %v fixed", second, newLink, oldLink)
+	}
+
+	// Every run is kept in the store
+	runs, err := daemon.Runner.Store.List()
+	if err != nil || len(runs) != 3 {
+		t.Errorf("Store.List() = %d runs, %v, want 3", len(runs), err)
+	}
+}
+
+func TestDaemon_Run(t *testing.T) {
+	site := httptest.NewServer(http.NotFoundHandler())
+	defer site.Close()
+
+	daemon := &Daemon{Runner: newTestRunner(t, site.URL), Profile: "fast", Interval: 20 * time.Millisecond}
+
+	// The daemon keeps crawling until it is stopped
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := daemon.Run(ctx); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	runs, err := daemon.Runner.Store.List()
+	if err != nil || len(runs) < 2 {
+		t.Errorf("Run() left %d runs, %v, want at least 2", len(runs), err)
+	}
+}
+
+func Test_diffDeadLinks(t *testing.T) {
+	a := crawler.DeadLink{URL: "https://kdlp.underground.software/a.html", ReferringURL: "https://kdlp.underground.software/index.html", StatusCode: 404}
+	b := crawler.DeadLink{URL: "https://kdlp.underground.software/b.html", ReferringURL: "https://kdlp.underground.software/index.html", StatusCode: 404}
+	bElsewhere := crawler.DeadLink{URL: b.URL, ReferringURL: "https://kdlp.underground.software/page.html", StatusCode: 404}
+	aGone := crawler.DeadLink{URL: a.URL, ReferringURL: a.ReferringURL, StatusCode: 410}
+
+	tests := []struct {
+		name      string
+		previous  []crawler.DeadLink
+		current   []crawler.DeadLink
+		wantNew   []crawler.DeadLink
+		wantFixed []crawler.DeadLink
+	}{
+		{
+			name:      "Unchanged",
+			previous:  []crawler.DeadLink{a, b},
+			current:   []crawler.DeadLink{b, a},
+			wantNew:   []crawler.DeadLink{},
+			wantFixed: []crawler.DeadLink{},
+		},
+		{
+			name:      "First run",
+			current:   []crawler.DeadLink{b, a},
+			wantNew:   []crawler.DeadLink{a, b},
+			wantFixed: []crawler.DeadLink{},
+		},
+		{
+			name:      "Same URL broken on another page",
+			previous:  []crawler.DeadLink{b},
+			current:   []crawler.DeadLink{b, bElsewhere},
+			wantNew:   []crawler.DeadLink{bElsewhere},
+			wantFixed: []crawler.DeadLink{},
+		},
+		{
+			name:      "Other status, same broken link",
+			previous:  []crawler.DeadLink{a},
+			current:   []crawler.DeadLink{aGone},
+			wantNew:   []crawler.DeadLink{},
+			wantFixed: []crawler.DeadLink{},
+		},
+		{
+			name:      "Fixed",
+			previous:  []crawler.DeadLink{a, b},
+			current:   []crawler.DeadLink{a},
+			wantNew:   []crawler.DeadLink{},
+			wantFixed: []crawler.DeadLink{b},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotNew, gotFixed := diffDeadLinks(tt.previous, tt.current)
+			if !reflect.DeepEqual(gotNew, tt.wantNew) || !reflect.DeepEqual(gotFixed, tt.wantFixed) {
+				t.Errorf("diffDeadLinks() = %v, %v, want %v, %v", gotNew, gotFixed, tt.wantNew, tt.wantFixed)
+			}
+		})
+	}
+}
+
+func TestCommandNotifier_Notify(t *testing.T) {
+	// The command saves the change it is given and its environment
+	out := filepath.Join(t.TempDir(), "change")
+	notifier := CommandNotifier{Command: []string{"sh", "-c", `cat > "$0.json" && echo "$WEBCRAWLER_RUN_ID $WEBCRAWLER_NEW $WEBCRAWLER_FIXED" > "$0.env"`, out}}
+
+	link := crawler.DeadLink{URL: "https://kdlp.underground.software/a.html", StatusCode: 404}
+	change := Change{Run: Run{ID: "20240101-000000"}, DeadLinks: []crawler.DeadLink{link}, New: []crawler.DeadLink{link}, Fixed: []crawler.DeadLink{}}
+	if err := notifier.Notify(context.Background(), change); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+
+	data, err := os.ReadFile(out + ".json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Change
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("command got %q: %v", data, err)
+	}
+	if got.Run.ID != change.Run.ID || !reflect.DeepEqual(got.New, change.New) {
+		t.Errorf("command got change %+v, want %+v", got, change)
+	}
+
+	env, err := os.ReadFile(out + ".env")
+	if want := "20240101-000000 1 0\n"; err != nil || string(env) != want {
+		t.Errorf("command got environment %q, %v, want %q", env, err, want)
+	}
+
+	// A failing command is an error
+	if err := (CommandNotifier{Command: []string{"false"}}).Notify(context.Background(), change); err == nil {
+		t.Error("Notify() with a failing command error = nil, want an error")
+	}
+}
@@ -0,0 +1,259 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/underground-software/KDLP_Webcrawler.git/crawler"
+)
+
+// Longest an SMTP session may take when the context has no deadline
+const smtpTimeout = time.Minute
+
+// EmailNotifier mails a plain text digest of the broken links, grouped by the page they are on,
+// for mailing lists. The digest is laid out like a patch review: one link per line and nothing
+// wrapped, so replies can quote the lines they are about.
+//
+// It is also a crawler.Reporter, mailing the dead links of a single crawl when there are any.
+type EmailNotifier struct {
+
+	// SMTP server, as host:port
+	Addr string
+
+	From string
+	To   []string
+
+	// Credentials for AUTH PLAIN, no authentication if Username is empty. They are only sent
+	// over TLS or to a server on localhost.
+	Username string
+	Password string
+
+	// Config of the STARTTLS upgrade, done whenever the server offers it. If nil, the server's
+	// certificate is checked against the host of Addr.
+	TLSConfig *tls.Config
+
+	// Put before the subject of every mail, "[KDLP_Webcrawler]" if empty
+	SubjectPrefix string
+}
+
+// Notify mails the digest of the change
+func (n *EmailNotifier) Notify(ctx context.Context, change Change) error {
+	return n.send(ctx, n.subject(change), digest(change))
+}
+
+// Report mails the dead links of a crawl that ran on its own, with no previous run to compare with
+func (n *EmailNotifier) Report(result *crawler.Result) error {
+	if len(result.DeadLinks) == 0 {
+		return nil
+	}
+
+	finished := time.Now()
+	run := Run{Status: StatusFinished, Started: finished.Add(-result.Elapsed), Finished: &finished, DeadLinks: len(result.DeadLinks)}
+	change := Change{Run: run, DeadLinks: result.DeadLinks, New: result.DeadLinks, Fixed: []crawler.DeadLink{}}
+	return n.Notify(context.Background(), change)
+}
+
+// Function to sum up a change in a subject line, such as "3 broken links on 2 pages (1 new, 2 fixed)"
+func (n *EmailNotifier) subject(change Change) string {
+	prefix := n.SubjectPrefix
+	if prefix == "" {
+		prefix = "[KDLP_Webcrawler]"
+	}
+
+	subject := "no broken links"
+	if len(change.DeadLinks) > 0 {
+		subject = fmt.Sprintf("%s on %s", plural(len(change.DeadLinks), "broken link"), plural(len(pagesOf(change.DeadLinks)), "page"))
+	}
+	if change.Previous != nil {
+		subject += fmt.Sprintf(" (%d new, %d fixed)", len(change.New), len(change.Fixed))
+	}
+	return prefix + " " + subject
+}
+
+// Function to write the body of the mail for a change. Links only get marked new when there is a
+// previous run they are new since.
+func digest(change Change) string {
+	var b strings.Builder
+
+	// Say what was crawled and what changed first, for readers of the list who stop there
+	crawl := "The crawl"
+	if change.Run.ID != "" {
+		crawl = "Run " + change.Run.ID
+	}
+	fmt.Fprintf(&b, "%s found %s on %s.\n", crawl, plural(len(change.DeadLinks), "broken link"), plural(len(pagesOf(change.DeadLinks)), "page"))
+	if change.Previous != nil {
+		fmt.Fprintf(&b, "%d new and %d fixed since run %s.\n", len(change.New), len(change.Fixed), change.Previous.ID)
+	}
+
+	isNew := make(map[crawler.DeadLink]bool, len(change.New))
+	if change.Previous != nil {
+		for _, link := range change.New {
+			isNew[link] = true
+		}
+	}
+	writeLinksByPage(&b, change.DeadLinks, func(link crawler.DeadLink) string {
+		line := fmt.Sprintf("%-5s %s", statusText(link.StatusCode), link.URL)
+		if isNew[link] {
+			line += " [new]"
+		}
+		return line
+	})
+
+	if len(change.Fixed) > 0 && change.Previous != nil {
+		fmt.Fprintf(&b, "\nFixed since run %s:\n", change.Previous.ID)
+		writeLinksByPage(&b, change.Fixed, func(link crawler.DeadLink) string {
+			return link.URL
+		})
+	}
+
+	// Standard signature separator, so mail clients leave the signature out of replies
+	b.WriteString("\n-- \nKDLP_Webcrawler\n")
+	return b.String()
+}
+
+// Writes links under the page they are on, pages and links in order, a blank line before each page
+func writeLinksByPage(b *strings.Builder, links []crawler.DeadLink, line func(crawler.DeadLink) string) {
+	byPage := make(map[string][]crawler.DeadLink)
+	for _, link := range links {
+		byPage[link.ReferringURL] = append(byPage[link.ReferringURL], link)
+	}
+
+	for _, page := range pagesOf(links) {
+		onPage := byPage[page]
+		sort.Slice(onPage, func(i, j int) bool {
+			return onPage[i].URL < onPage[j].URL
+		})
+
+		if page == "" {
+			page = "(not linked from any page)"
+		}
+		fmt.Fprintf(b, "\n%s\n", page)
+		for _, link := range onPage {
+			fmt.Fprintf(b, "    %s\n", line(link))
+		}
+	}
+}
+
+// Function to list the pages links are on, in order
+func pagesOf(links []crawler.DeadLink) []string {
+	seen := make(map[string]bool)
+	var pages []string
+	for _, link := range links {
+		if !seen[link.ReferringURL] {
+			seen[link.ReferringURL] = true
+			pages = append(pages, link.ReferringURL)
+		}
+	}
+	sort.Strings(pages)
+	return pages
+}
+
+// Function to show a status code, links that got no response have none
+func statusText(code int) string {
+	if code == 0 {
+		return "error"
+	}
+	return fmt.Sprint(code)
+}
+
+// Function to count things in words, such as "1 page" or "2 pages"
+func plural(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+// Sends one mail over a new SMTP session, upgrading it to TLS when the server offers it
+func (n *EmailNotifier) send(ctx context.Context, subject, body string) error {
+	if len(n.To) == 0 {
+		return fmt.Errorf("no recipients for the digest")
+	}
+	host, _, err := net.SplitHostPort(n.Addr)
+	if err != nil {
+		return fmt.Errorf("invalid SMTP server address %q: %w", n.Addr, err)
+	}
+
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", n.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to SMTP server: %w", err)
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(smtpTimeout)
+	}
+	conn.SetDeadline(deadline)
+
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to start SMTP session: %w", err)
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		config := &tls.Config{}
+		if n.TLSConfig != nil {
+			config = n.TLSConfig.Clone()
+		}
+		if config.ServerName == "" {
+			config.ServerName = host
+		}
+		if err := c.StartTLS(config); err != nil {
+			return fmt.Errorf("failed to start TLS: %w", err)
+		}
+	}
+
+	if n.Username != "" {
+		if ok, _ := c.Extension("AUTH"); !ok {
+			return fmt.Errorf("SMTP server %s does not support authentication", n.Addr)
+		}
+		if err := c.Auth(smtp.PlainAuth("", n.Username, n.Password, host)); err != nil {
+			return fmt.Errorf("failed to authenticate: %w", err)
+		}
+	}
+
+	if err := c.Mail(n.From); err != nil {
+		return fmt.Errorf("SMTP server refused sender %s: %w", n.From, err)
+	}
+	for _, to := range n.To {
+		if err := c.Rcpt(to); err != nil {
+			return fmt.Errorf("SMTP server refused recipient %s: %w", to, err)
+		}
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(message(n.From, n.To, subject, body)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("SMTP server refused the digest: %w", err)
+	}
+
+	return c.Quit()
+}
+
+// Function to put the headers in front of the body. Plain UTF-8 text, which lists and patch tools handle as is.
+func message(from string, to []string, subject, body string) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	return []byte(b.String())
+}
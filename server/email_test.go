@@ -0,0 +1,295 @@
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"math/big"
+	"net"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/underground-software/KDLP_Webcrawler.git/crawler"
+)
+
+// Mail as received by the SMTP stand-in
+type receivedMail struct {
+	from string
+	to   []string
+	data string
+
+	// Whether the session was upgraded to TLS, and the credentials it authenticated with
+	tls  bool
+	auth string
+}
+
+// SMTP stand-in serving one session on localhost, offering STARTTLS when it has a certificate
+// and AUTH PLAIN always. The mail it receives, if any, is sent to the returned channel.
+func newSMTPStandIn(t *testing.T, cert *tls.Certificate) (string, <-chan receivedMail) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	mails := make(chan receivedMail, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		var mail receivedMail
+		tp := textproto.NewConn(conn)
+		tp.PrintfLine("220 localhost ESMTP stand-in")
+		for {
+			line, err := tp.ReadLine()
+			if err != nil {
+				return
+			}
+			verb, arg, _ := strings.Cut(line, " ")
+			switch strings.ToUpper(verb) {
+			case "EHLO":
+				tp.PrintfLine("250-localhost")
+				if cert != nil && !mail.tls {
+					tp.PrintfLine("250-STARTTLS")
+				}
+				tp.PrintfLine("250 AUTH PLAIN")
+			case "STARTTLS":
+				tp.PrintfLine("220 go ahead")
+				tlsConn := tls.Server(conn, &tls.Config{Certificates: []tls.Certificate{*cert}})
+				if err := tlsConn.Handshake(); err != nil {
+					return
+				}
+				conn, mail.tls = tlsConn, true
+				tp = textproto.NewConn(tlsConn)
+			case "AUTH":
+				creds, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(arg, "PLAIN "))
+				mail.auth = string(creds)
+				tp.PrintfLine("235 authenticated")
+			case "MAIL":
+				mail.from = strings.Trim(strings.TrimPrefix(arg, "FROM:"), "<>")
+				tp.PrintfLine("250 ok")
+			case "RCPT":
+				mail.to = append(mail.to, strings.Trim(strings.TrimPrefix(arg, "TO:"), "<>"))
+				tp.PrintfLine("250 ok")
+			case "DATA":
+				tp.PrintfLine("354 send it")
+				data, err := tp.ReadDotBytes()
+				if err != nil {
+					return
+				}
+				mail.data = string(data)
+				tp.PrintfLine("250 queued")
+				mails <- mail
+			case "QUIT":
+				tp.PrintfLine("221 bye")
+				return
+			default:
+				tp.PrintfLine("502 unknown command")
+			}
+		}
+	}()
+
+	return ln.Addr().String(), mails
+}
+
+// Creates a self-signed certificate for 127.0.0.1 and a pool trusting it
+func newTestCertificate(t *testing.T) (*tls.Certificate, *x509.CertPool) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "SMTP stand-in"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(leaf)
+	return &tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, pool
+}
+
+func TestEmailNotifier_Notify(t *testing.T) {
+	link := crawler.DeadLink{URL: "https://kdlp.underground.software/a.html", ReferringURL: "https://kdlp.underground.software/index.html", StatusCode: 404}
+	change := Change{Run: Run{ID: "20240102-000000"}, Previous: &Run{ID: "20240101-000000"}, DeadLinks: []crawler.DeadLink{link}, New: []crawler.DeadLink{link}, Fixed: []crawler.DeadLink{}}
+	cert, pool := newTestCertificate(t)
+
+	tests := []struct {
+		name     string
+		cert     *tls.Certificate
+		username string
+		wantTLS  bool
+		wantAuth string
+	}{
+		{name: "Plain", cert: nil},
+		{name: "STARTTLS", cert: cert, wantTLS: true},
+		{name: "STARTTLS with auth", cert: cert, username: "crawler", wantTLS: true, wantAuth: "\x00crawler\x00hunter2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr, mails := newSMTPStandIn(t, tt.cert)
+			notifier := &EmailNotifier{
+				Addr:      addr,
+				From:      "crawler@kdlp.underground.software",
+				To:        []string{"staff@kdlp.underground.software", "list@kdlp.underground.software"},
+				Username:  tt.username,
+				Password:  "hunter2",
+				TLSConfig: &tls.Config{RootCAs: pool},
+			}
+
+			if err := notifier.Notify(context.Background(), change); err != nil {
+				t.Fatalf("Notify() error = %v", err)
+			}
+			mail := <-mails
+
+			if mail.tls != tt.wantTLS || mail.auth != tt.wantAuth {
+				t.Errorf("Notify() session TLS = %v, auth = %q, want %v, %q", mail.tls, mail.auth, tt.wantTLS, tt.wantAuth)
+			}
+			if mail.from != notifier.From || strings.Join(mail.to, ",") != strings.Join(notifier.To, ",") {
+				t.Errorf("Notify() sent from %s to %v, want from %s to %v", mail.from, mail.to, notifier.From, notifier.To)
+			}
+			for _, want := range []string{
+				"To: staff@kdlp.underground.software, list@kdlp.underground.software\n",
+				"Subject: [KDLP_Webcrawler] 1 broken link on 1 page (1 new, 0 fixed)\n",
+				"Content-Type: text/plain; charset=utf-8\n",
+				"\nRun 20240102-000000 found 1 broken link on 1 page.\n",
+			} {
+				if !strings.Contains(mail.data, want) {
+					t.Errorf("Notify() mail is missing %q, got:\n%s", want, mail.data)
+				}
+			}
+		})
+	}
+}
+
+func TestEmailNotifier_errors(t *testing.T) {
+	change := Change{DeadLinks: []crawler.DeadLink{{URL: "https://kdlp.underground.software/a.html", StatusCode: 404}}}
+
+	// A certificate the client does not trust stops the session
+	cert, _ := newTestCertificate(t)
+	addr, _ := newSMTPStandIn(t, cert)
+	notifier := &EmailNotifier{Addr: addr, From: "crawler@example.com", To: []string{"staff@example.com"}}
+	if err := notifier.Notify(context.Background(), change); err == nil {
+		t.Error("Notify() with an untrusted certificate error = nil, want an error")
+	}
+
+	// There is nobody to mail
+	notifier = &EmailNotifier{Addr: addr, From: "crawler@example.com"}
+	if err := notifier.Notify(context.Background(), change); err == nil {
+		t.Error("Notify() without recipients error = nil, want an error")
+	}
+}
+
+func TestEmailNotifier_Report(t *testing.T) {
+	addr, mails := newSMTPStandIn(t, nil)
+	notifier := &EmailNotifier{Addr: addr, From: "crawler@example.com", To: []string{"staff@example.com"}, SubjectPrefix: "[kdlp]"}
+
+	// Without dead links there is nothing to mail
+	if err := notifier.Report(&crawler.Result{}); err != nil {
+		t.Fatalf("Report() error = %v", err)
+	}
+
+	links := []crawler.DeadLink{{URL: "https://kdlp.underground.software/a.html", ReferringURL: "https://kdlp.underground.software/index.html", StatusCode: 404}}
+	if err := notifier.Report(&crawler.Result{DeadLinks: links}); err != nil {
+		t.Fatalf("Report() error = %v", err)
+	}
+	mail := <-mails
+	if want := "Subject: [kdlp] 1 broken link on 1 page\n"; !strings.Contains(mail.data, want) {
+		t.Errorf("Report() mail is missing %q, got:\n%s", want, mail.data)
+	}
+}
+
+func Test_digest(t *testing.T) {
+	index := "https://kdlp.underground.software/index.html"
+	page := "https://kdlp.underground.software/page.html"
+	a := crawler.DeadLink{URL: "https://kdlp.underground.software/a.html", ReferringURL: index, StatusCode: 404}
+	b := crawler.DeadLink{URL: "https://kdlp.underground.software/b.html", ReferringURL: index, StatusCode: 410}
+	down := crawler.DeadLink{URL: "https://down.example.com/", ReferringURL: page}
+	fixed := crawler.DeadLink{URL: "https://kdlp.underground.software/c.html", ReferringURL: page, StatusCode: 404}
+	sitemap := crawler.DeadLink{URL: "https://kdlp.underground.software/lost.html", StatusCode: 404}
+
+	// Kept out of the raw strings, the separator ends with a space
+	signature := "\n-- \nKDLP_Webcrawler\n"
+
+	tests := []struct {
+		name   string
+		change Change
+		want   string
+	}{
+		{
+			name: "Change since a previous run",
+			change: Change{
+				Run:       Run{ID: "20240102-000000"},
+				Previous:  &Run{ID: "20240101-000000"},
+				DeadLinks: []crawler.DeadLink{down, b, a},
+				New:       []crawler.DeadLink{a, down},
+				Fixed:     []crawler.DeadLink{fixed},
+			},
+			want: `Run 20240102-000000 found 3 broken links on 2 pages.
+2 new and 1 fixed since run 20240101-000000.
+
+https://kdlp.underground.software/index.html
+    404   https://kdlp.underground.software/a.html [new]
+    410   https://kdlp.underground.software/b.html
+
+https://kdlp.underground.software/page.html
+    error https://down.example.com/ [new]
+
+Fixed since run 20240101-000000:
+
+https://kdlp.underground.software/page.html
+    https://kdlp.underground.software/c.html
+` + signature,
+		},
+		{
+			name: "Crawl on its own",
+			change: Change{
+				DeadLinks: []crawler.DeadLink{a, sitemap},
+				New:       []crawler.DeadLink{a, sitemap},
+			},
+			want: `The crawl found 2 broken links on 2 pages.
+
+(not linked from any page)
+    404   https://kdlp.underground.software/lost.html
+
+https://kdlp.underground.software/index.html
+    404   https://kdlp.underground.software/a.html
+` + signature,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := digest(tt.change); got != tt.want {
+				t.Errorf("digest() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,269 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/underground-software/KDLP_Webcrawler.git/crawler"
+)
+
+// Upper bounds in seconds of the buckets of the fetch latency histograms
+var fetchDurationBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// Upper bounds in seconds of the buckets of the crawl duration histogram
+var crawlDurationBuckets = []float64{10, 30, 60, 300, 600, 1800, 3600, 7200}
+
+// Metrics counts what the crawls of a runner do and serves the counts in the Prometheus text format
+type Metrics struct {
+	mu sync.Mutex
+
+	pagesFetched uint64
+
+	// Links checked by status class: 2xx, 3xx, 4xx, 5xx or error
+	linksChecked map[string]uint64
+
+	// Requests sent again, such as a GET after a server refused HEAD
+	retries uint64
+
+	// Broken links found by the last finished run, internal and external
+	brokenLinks map[string]int
+
+	// Time to get a response, by host
+	fetchDuration map[string]*histogram
+
+	crawlDuration *histogram
+
+	// Runs over, by how they ended
+	runs map[RunStatus]uint64
+
+	runsInProgress int
+}
+
+// NewMetrics returns metrics with nothing counted yet
+func NewMetrics() *Metrics {
+	return &Metrics{
+		linksChecked:  make(map[string]uint64),
+		brokenLinks:   map[string]int{"internal": 0, "external": 0},
+		fetchDuration: make(map[string]*histogram),
+		crawlDuration: newHistogram(crawlDurationBuckets),
+		runs:          make(map[RunStatus]uint64),
+	}
+}
+
+// Starts counting for a new run, the returned observer is told about its crawl
+func (m *Metrics) startRun() *runMetrics {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.runsInProgress++
+	return &runMetrics{metrics: m, brokenLinks: map[string]int{"internal": 0, "external": 0}}
+}
+
+// Counts a run that is over. Only a finished run says how many links are broken now.
+func (m *Metrics) finishRun(rm *runMetrics, run Run) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.runsInProgress--
+	m.runs[run.Status]++
+	if run.Finished != nil {
+		m.crawlDuration.observe(run.Finished.Sub(run.Started).Seconds())
+	}
+	if run.Status == StatusFinished {
+		m.brokenLinks = rm.brokenLinks
+	}
+}
+
+// Observer counting the crawl of one run
+type runMetrics struct {
+	crawler.NopObserver
+	metrics *Metrics
+
+	// Broken links found so far, guarded by the metrics' lock
+	brokenLinks map[string]int
+}
+
+func (rm *runMetrics) OnPageFetched(crawler.Page) {
+	rm.metrics.mu.Lock()
+	defer rm.metrics.mu.Unlock()
+
+	rm.metrics.pagesFetched++
+}
+
+func (rm *runMetrics) OnLinkChecked(check crawler.LinkCheck) {
+	m := rm.metrics
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.linksChecked[statusClass(check)]++
+	m.retries += uint64(check.Retries)
+
+	// Checks that failed before any request was sent took no time worth counting
+	if check.Duration > 0 {
+		host := "unknown"
+		if u, err := url.Parse(check.URL); err == nil && u.Host != "" {
+			host = u.Host
+		}
+		if m.fetchDuration[host] == nil {
+			m.fetchDuration[host] = newHistogram(fetchDurationBuckets)
+		}
+		m.fetchDuration[host].observe(check.Duration.Seconds())
+	}
+}
+
+func (rm *runMetrics) OnFinding(finding crawler.Finding) {
+	if finding.Kind != crawler.FindingDeadLink && finding.Kind != crawler.FindingSoft404 {
+		return
+	}
+
+	rm.metrics.mu.Lock()
+	defer rm.metrics.mu.Unlock()
+
+	if finding.Internal {
+		rm.brokenLinks["internal"]++
+	} else {
+		rm.brokenLinks["external"]++
+	}
+}
+
+// Function to group the outcome of a check by the first digit of its status code
+func statusClass(check crawler.LinkCheck) string {
+	if check.Err != nil || check.StatusCode < 100 || check.StatusCode > 599 {
+		return "error"
+	}
+	return strconv.Itoa(check.StatusCode/100) + "xx"
+}
+
+// ServeHTTP writes the metrics in the Prometheus text format
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var buf bytes.Buffer
+	m.WriteTo(&buf)
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Write(buf.Bytes())
+}
+
+// WriteTo writes the metrics in the Prometheus text format, with labels in a stable order
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var buf bytes.Buffer
+
+	writeHeader(&buf, "webcrawler_pages_fetched_total", "counter", "Pages fetched and scanned for links.")
+	fmt.Fprintf(&buf, "webcrawler_pages_fetched_total %d\n", m.pagesFetched)
+
+	writeHeader(&buf, "webcrawler_links_checked_total", "counter", "URLs checked, by status class.")
+	for _, class := range sortedKeys(m.linksChecked) {
+		fmt.Fprintf(&buf, "webcrawler_links_checked_total{class=%q} %d\n", class, m.linksChecked[class])
+	}
+
+	writeHeader(&buf, "webcrawler_broken_links", "gauge", "Broken links found by the last finished crawl, internal or external.")
+	for _, scope := range sortedKeys(m.brokenLinks) {
+		fmt.Fprintf(&buf, "webcrawler_broken_links{scope=%q} %d\n", scope, m.brokenLinks[scope])
+	}
+
+	writeHeader(&buf, "webcrawler_retries_total", "counter", "Requests sent again, such as a GET after a server refused HEAD.")
+	fmt.Fprintf(&buf, "webcrawler_retries_total %d\n", m.retries)
+
+	writeHeader(&buf, "webcrawler_fetch_duration_seconds", "histogram", "Time to get a response, by host.")
+	for _, host := range sortedKeys(m.fetchDuration) {
+		m.fetchDuration[host].write(&buf, "webcrawler_fetch_duration_seconds", `host="`+escapeLabel(host)+`"`)
+	}
+
+	writeHeader(&buf, "webcrawler_crawl_duration_seconds", "histogram", "Time crawls took, cancelled ones included.")
+	m.crawlDuration.write(&buf, "webcrawler_crawl_duration_seconds", "")
+
+	writeHeader(&buf, "webcrawler_runs_total", "counter", "Runs over, by how they ended.")
+	statuses := make(map[string]uint64, len(m.runs))
+	for status, n := range m.runs {
+		statuses[string(status)] = n
+	}
+	for _, status := range sortedKeys(statuses) {
+		fmt.Fprintf(&buf, "webcrawler_runs_total{status=%q} %d\n", status, statuses[status])
+	}
+
+	writeHeader(&buf, "webcrawler_runs_in_progress", "gauge", "Runs crawling right now.")
+	fmt.Fprintf(&buf, "webcrawler_runs_in_progress %d\n", m.runsInProgress)
+
+	n, err := w.Write(buf.Bytes())
+	return int64(n), err
+}
+
+// Writes the HELP and TYPE lines of a metric
+func writeHeader(w io.Writer, name, kind, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+// Function to escape a label value for the Prometheus text format
+func escapeLabel(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+// Returns the keys of a map in order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Prometheus histogram with fixed buckets
+type histogram struct {
+
+	// Upper bounds of the buckets, in increasing order
+	bounds []float64
+
+	// Number of observations in each bucket, not cumulative
+	counts []uint64
+
+	count uint64
+	sum   float64
+}
+
+// Function to create an empty histogram
+func newHistogram(bounds []float64) *histogram {
+	return &histogram{bounds: bounds, counts: make([]uint64, len(bounds))}
+}
+
+// Adds an observation to the histogram, values above the last bound only count in +Inf
+func (h *histogram) observe(value float64) {
+	h.count++
+	h.sum += value
+	for i, bound := range h.bounds {
+		if value <= bound {
+			h.counts[i]++
+			return
+		}
+	}
+}
+
+// Writes the buckets, sum and count of the histogram, labels are added to every line
+func (h *histogram) write(w io.Writer, name, labels string) {
+	prefix := labels
+	if prefix != "" {
+		prefix += ","
+	}
+
+	var cumulative uint64
+	for i, bound := range h.bounds {
+		cumulative += h.counts[i]
+		fmt.Fprintf(w, "%s_bucket{%sle=\"%s\"} %d\n", name, prefix, strconv.FormatFloat(bound, 'g', -1, 64), cumulative)
+	}
+	fmt.Fprintf(w, "%s_bucket{%sle=\"+Inf\"} %d\n", name, prefix, h.count)
+
+	if labels != "" {
+		labels = "{" + labels + "}"
+	}
+	fmt.Fprintf(w, "%s_sum%s %s\n", name, labels, strconv.FormatFloat(h.sum, 'g', -1, 64))
+	fmt.Fprintf(w, "%s_count%s %d\n", name, labels, h.count)
+}
@@ -0,0 +1,157 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/underground-software/KDLP_Webcrawler.git/crawler"
+)
+
+func TestMetrics_WriteTo(t *testing.T) {
+	m := NewMetrics()
+
+	// One finished run that fetched a page and found a broken link on each side of the site
+	rm := m.startRun()
+	rm.OnPageFetched(crawler.Page{URL: "https://kdlp.underground.software/index.html", StatusCode: 200})
+	rm.OnLinkChecked(crawler.LinkCheck{URL: "https://kdlp.underground.software/index.html", StatusCode: 200, Duration: 80 * time.Millisecond})
+	rm.OnLinkChecked(crawler.LinkCheck{URL: "https://kdlp.underground.software/gone.html", StatusCode: 404, Duration: 300 * time.Millisecond})
+	rm.OnLinkChecked(crawler.LinkCheck{URL: "https://example.com/", StatusCode: 200, Duration: 2 * time.Second, Retries: 1})
+	rm.OnLinkChecked(crawler.LinkCheck{URL: "https://down.example.com/", Err: errors.New("connection refused")})
+	rm.OnFinding(crawler.Finding{Kind: crawler.FindingDeadLink, URL: "https://kdlp.underground.software/gone.html", StatusCode: 404, Internal: true})
+	rm.OnFinding(crawler.Finding{Kind: crawler.FindingDeadLink, URL: "https://down.example.com/"})
+	rm.OnFinding(crawler.Finding{Kind: crawler.FindingOrphan, URL: "https://kdlp.underground.software/lost.html", Internal: true})
+
+	started := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	finished := started.Add(45 * time.Second)
+	m.finishRun(rm, Run{Status: StatusFinished, Started: started, Finished: &finished})
+
+	// A cancelled run is counted, but does not replace the broken links of the finished one
+	rm = m.startRun()
+	rm.OnFinding(crawler.Finding{Kind: crawler.FindingDeadLink, URL: "https://kdlp.underground.software/other.html", Internal: true})
+	m.finishRun(rm, Run{Status: StatusCancelled, Started: started, Finished: &finished})
+
+	// A run in progress
+	m.startRun()
+
+	var out strings.Builder
+	if _, err := m.WriteTo(&out); err != nil {
+		t.Fatalf("WriteTo() error = %v", err)
+	}
+	got := out.String()
+
+	want := []string{
+		"# TYPE webcrawler_pages_fetched_total counter",
+		"webcrawler_pages_fetched_total 1",
+		`webcrawler_links_checked_total{class="2xx"} 2`,
+		`webcrawler_links_checked_total{class="4xx"} 1`,
+		`webcrawler_links_checked_total{class="error"} 1`,
+		"# TYPE webcrawler_broken_links gauge",
+		`webcrawler_broken_links{scope="external"} 1`,
+		`webcrawler_broken_links{scope="internal"} 1`,
+		"webcrawler_retries_total 1",
+		"# TYPE webcrawler_fetch_duration_seconds histogram",
+		`webcrawler_fetch_duration_seconds_bucket{host="example.com",le="1"} 0`,
+		`webcrawler_fetch_duration_seconds_bucket{host="example.com",le="2.5"} 1`,
+		`webcrawler_fetch_duration_seconds_bucket{host="kdlp.underground.software",le="0.05"} 0`,
+		`webcrawler_fetch_duration_seconds_bucket{host="kdlp.underground.software",le="0.1"} 1`,
+		`webcrawler_fetch_duration_seconds_bucket{host="kdlp.underground.software",le="0.5"} 2`,
+		`webcrawler_fetch_duration_seconds_bucket{host="kdlp.underground.software",le="+Inf"} 2`,
+		`webcrawler_fetch_duration_seconds_sum{host="kdlp.underground.software"} 0.38`,
+		`webcrawler_fetch_duration_seconds_count{host="kdlp.underground.software"} 2`,
+		`webcrawler_crawl_duration_seconds_bucket{le="30"} 0`,
+		`webcrawler_crawl_duration_seconds_bucket{le="60"} 2`,
+		"webcrawler_crawl_duration_seconds_sum 90",
+		"webcrawler_crawl_duration_seconds_count 2",
+		`webcrawler_runs_total{status="cancelled"} 1`,
+		`webcrawler_runs_total{status="finished"} 1`,
+		"webcrawler_runs_in_progress 1",
+	}
+	for _, line := range want {
+		if !strings.Contains(got, line+"\n") {
+			t.Errorf("WriteTo() is missing %q, got:\n%s", line, got)
+		}
+	}
+
+	// Checks that got no response have no latency to count
+	if strings.Contains(got, "down.example.com") {
+		t.Errorf("WriteTo() has a latency for a failed check, got:\n%s", got)
+	}
+}
+
+func Test_statusClass(t *testing.T) {
+	tests := []struct {
+		name  string
+		check crawler.LinkCheck
+		want  string
+	}{
+		{name: "OK", check: crawler.LinkCheck{StatusCode: 200}, want: "2xx"},
+		{name: "Redirect", check: crawler.LinkCheck{StatusCode: 301}, want: "3xx"},
+		{name: "Not found", check: crawler.LinkCheck{StatusCode: 404}, want: "4xx"},
+		{name: "Server error", check: crawler.LinkCheck{StatusCode: 503}, want: "5xx"},
+		{name: "No response", check: crawler.LinkCheck{Err: errors.New("timeout")}, want: "error"},
+		{name: "No status", check: crawler.LinkCheck{}, want: "error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := statusClass(tt.check); got != tt.want {
+				t.Errorf("statusClass() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandler_metrics(t *testing.T) {
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/index.html" {
+			fmt.Fprint(w, `<a href="/missing.html">Missing</a>`)
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer site.Close()
+
+	// Without metrics there is no endpoint
+	runner := newTestRunner(t, site.URL)
+	rec := httptest.NewRecorder()
+	NewHandler(runner).ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("GET /metrics without metrics = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+
+	runner.Metrics = NewMetrics()
+	if _, _, err := runner.Run(context.Background(), "fast", ""); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	rec = httptest.NewRecorder()
+	NewHandler(runner).ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /metrics = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if got := rec.Header().Get("Content-Type"); !strings.HasPrefix(got, "text/plain; version=0.0.4") {
+		t.Errorf("GET /metrics Content-Type = %q, want the Prometheus text format", got)
+	}
+
+	body := rec.Body.String()
+	for _, line := range []string{
+		"webcrawler_pages_fetched_total 1",
+		`webcrawler_links_checked_total{class="2xx"} 1`,
+		`webcrawler_links_checked_total{class="4xx"} 1`,
+		`webcrawler_broken_links{scope="internal"} 1`,
+		`webcrawler_broken_links{scope="external"} 0`,
+		`webcrawler_runs_total{status="finished"} 1`,
+		"webcrawler_runs_in_progress 0",
+		"webcrawler_crawl_duration_seconds_count 1",
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("GET /metrics is missing %q, got:\n%s", line, body)
+		}
+	}
+}
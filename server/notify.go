@@ -0,0 +1,113 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+
+	"github.com/underground-software/KDLP_Webcrawler.git/crawler"
+)
+
+// Change is what changed in the broken links of the site between two finished runs
+type Change struct {
+
+	// Run that found the change
+	Run Run `json:"run"`
+
+	// Last finished run before it with the same profile, nil if there is none
+	Previous *Run `json:"previous,omitempty"`
+
+	// Every broken link the run found
+	DeadLinks []crawler.DeadLink `json:"dead_links"`
+
+	// Broken links the previous run did not find
+	New []crawler.DeadLink `json:"new"`
+
+	// Broken links of the previous run that are gone
+	Fixed []crawler.DeadLink `json:"fixed"`
+}
+
+// Notifier is told when the broken links of the site change
+type Notifier interface {
+	Notify(ctx context.Context, change Change) error
+}
+
+// Function to compare the broken links of two runs. A link counts as the same broken link
+// when it points to the same URL from the same page, whatever status it answered.
+func diffDeadLinks(previous, current []crawler.DeadLink) (added, fixed []crawler.DeadLink) {
+	key := func(link crawler.DeadLink) string {
+		return link.URL + " " + link.ReferringURL
+	}
+
+	before := make(map[string]bool, len(previous))
+	for _, link := range previous {
+		before[key(link)] = true
+	}
+	now := make(map[string]bool, len(current))
+	for _, link := range current {
+		now[key(link)] = true
+	}
+
+	added, fixed = []crawler.DeadLink{}, []crawler.DeadLink{}
+	for _, link := range current {
+		if !before[key(link)] {
+			added = append(added, link)
+			before[key(link)] = true
+		}
+	}
+	for _, link := range previous {
+		if !now[key(link)] {
+			fixed = append(fixed, link)
+			now[key(link)] = true
+		}
+	}
+
+	// Runs of the colly engine find links in no particular order, keep notifications stable
+	for _, links := range [][]crawler.DeadLink{added, fixed} {
+		sort.Slice(links, func(i, j int) bool {
+			return key(links[i]) < key(links[j])
+		})
+	}
+	return added, fixed
+}
+
+// CommandNotifier runs a command for every change, with the change as JSON on its standard input.
+// The command also gets the run ID and the number of new and fixed broken links in the
+// environment variables WEBCRAWLER_RUN_ID, WEBCRAWLER_NEW and WEBCRAWLER_FIXED.
+type CommandNotifier struct {
+
+	// Program and its arguments, run without a shell
+	Command []string
+}
+
+// Notify runs the command and waits for it to exit
+func (n CommandNotifier) Notify(ctx context.Context, change Change) error {
+	if len(n.Command) == 0 {
+		return fmt.Errorf("no notification command")
+	}
+
+	payload, err := json.Marshal(change)
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.CommandContext(ctx, n.Command[0], n.Command[1:]...)
+	cmd.Stdin = bytes.NewReader(payload)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(),
+		"WEBCRAWLER_RUN_ID="+change.Run.ID,
+		"WEBCRAWLER_NEW="+strconv.Itoa(len(change.New)),
+		"WEBCRAWLER_FIXED="+strconv.Itoa(len(change.Fixed)),
+	)
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("notification command %s failed: %w", n.Command[0], err)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package server
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/underground-software/KDLP_Webcrawler.git/crawler"
+)
+
+// Name of the profile that uses the default config when there is no file for it
+const DefaultProfile = "default"
+
+// Profiles are named crawler configs: the profile "nightly" is the config file nightly.json in Dir
+type Profiles struct {
+	Dir string
+}
+
+// Load returns the config of the named profile. The default profile falls back to the
+// default config when Dir has no default.json.
+func (p Profiles) Load(name string) (*crawler.Config, error) {
+	if name == "" {
+		name = DefaultProfile
+	}
+	if !validName(name) {
+		return nil, fmt.Errorf("invalid profile name %q", name)
+	}
+
+	if p.Dir != "" {
+		path := filepath.Join(p.Dir, name+".json")
+		if _, err := os.Stat(path); err == nil {
+			return crawler.LoadConfig(path)
+		}
+	}
+
+	if name == DefaultProfile {
+		return crawler.DefaultConfig(), nil
+	}
+	return nil, fmt.Errorf("unknown profile %q", name)
+}
+
+// Names returns the names of every profile, the default one included
+func (p Profiles) Names() ([]string, error) {
+	names := []string{DefaultProfile}
+	if p.Dir == "" {
+		return names, nil
+	}
+
+	entries, err := os.ReadDir(p.Dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".json")
+		if ok && !entry.IsDir() && name != DefaultProfile && validName(name) {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names[1:])
+	return names, nil
+}
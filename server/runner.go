@@ -0,0 +1,295 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/underground-software/KDLP_Webcrawler.git/crawler"
+)
+
+// ErrNotRunning is returned when cancelling a run that is already over
+var ErrNotRunning = errors.New("run is not running")
+
+// Runner runs crawls of one site in the background and keeps their results in a store
+type Runner struct {
+	Store    *Store
+	Profiles Profiles
+
+	// Site every run crawls, see crawler.Options
+	Domain  string
+	HomeURL string
+
+	// Engine used when a run does not name one
+	DefaultEngine string
+
+	// Told about every run's crawl as it goes, on top of the runner's own progress tracking
+	Observers []crawler.Observer
+
+	// Counts what every run does for the /metrics endpoint, nil to count nothing
+	Metrics *Metrics
+
+	mu     sync.Mutex
+	active map[string]*activeRun
+
+	// Background runs, waited for by Shutdown
+	wg sync.WaitGroup
+}
+
+// A run in progress, with the state polled by status requests
+type activeRun struct {
+	run    Run
+	cancel context.CancelFunc
+}
+
+// Start starts a crawl with the named profile and engine in the background and returns the new run
+func (r *Runner) Start(profile, engine string) (Run, error) {
+	run, prepared, err := r.prepare(profile, engine)
+	if err != nil {
+		return Run{}, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r.track(run, cancel)
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		defer cancel()
+		r.execute(ctx, run, prepared)
+	}()
+
+	return run, nil
+}
+
+// Run crawls with the named profile and engine until the crawl is over or ctx is done.
+// It returns the run as stored together with the result of the crawl.
+func (r *Runner) Run(ctx context.Context, profile, engine string) (Run, *crawler.Result, error) {
+	run, prepared, err := r.prepare(profile, engine)
+	if err != nil {
+		return Run{}, nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	r.track(run, cancel)
+	r.wg.Add(1)
+	defer r.wg.Done()
+
+	return r.execute(ctx, run, prepared)
+}
+
+// What a run needs to crawl, checked before the run is created
+type preparedRun struct {
+	engine crawler.Engine
+	config *crawler.Config
+}
+
+// Checks the profile and engine and creates the run in the store
+func (r *Runner) prepare(profile, engine string) (Run, preparedRun, error) {
+	if profile == "" {
+		profile = DefaultProfile
+	}
+	if engine == "" {
+		engine = r.DefaultEngine
+	}
+	if engine == "" {
+		engine = "custom"
+	}
+
+	e, err := crawler.NewEngine(engine)
+	if err != nil {
+		return Run{}, preparedRun{}, err
+	}
+	cfg, err := r.Profiles.Load(profile)
+	if err != nil {
+		return Run{}, preparedRun{}, err
+	}
+
+	run, err := r.Store.Create(profile, engine)
+	if err != nil {
+		return Run{}, preparedRun{}, fmt.Errorf("failed to create run: %w", err)
+	}
+
+	return run, preparedRun{engine: e, config: cfg}, nil
+}
+
+// Adds a run to the active runs so its progress can be polled and it can be cancelled
+func (r *Runner) track(run Run, cancel context.CancelFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.active == nil {
+		r.active = make(map[string]*activeRun)
+	}
+	r.active[run.ID] = &activeRun{run: run, cancel: cancel}
+}
+
+// Crawls for a run, writes its reports to the run's directory and saves how it ended
+func (r *Runner) execute(ctx context.Context, run Run, prepared preparedRun) (Run, *crawler.Result, error) {
+	dir := r.Store.Dir(run.ID)
+	log.Println("Starting run", run.ID, "with profile", run.Profile, "and engine", run.Engine)
+
+	observers := append([]crawler.Observer{
+		&progressObserver{runner: r, id: run.ID},
+		&crawler.DeadLinkFile{Path: filepath.Join(dir, "dead_links.txt")},
+	}, r.Observers...)
+	var metrics *runMetrics
+	if r.Metrics != nil {
+		metrics = r.Metrics.startRun()
+		observers = append(observers, metrics)
+	}
+
+	result, err := prepared.engine.Crawl(ctx, crawler.Options{
+		Domain:    r.Domain,
+		HomeURL:   r.HomeURL,
+		Config:    prepared.config,
+		Observers: observers,
+	})
+
+	// Write the reports before the run is marked over, so they are there once it is
+	if result != nil {
+		if reportErr := (crawler.TextReporter{Dir: dir, Out: io.Discard}).Report(result); reportErr != nil && err == nil {
+			err = reportErr
+		}
+		if saveErr := r.Store.SaveDeadLinks(run.ID, result.DeadLinks); saveErr != nil && err == nil {
+			err = saveErr
+		}
+	}
+
+	r.mu.Lock()
+	run = r.active[run.ID].run
+	delete(r.active, run.ID)
+	r.mu.Unlock()
+
+	finished := time.Now()
+	run.Finished = &finished
+	switch {
+	case errors.Is(err, context.Canceled):
+		run.Status = StatusCancelled
+	case err != nil:
+		run.Status = StatusFailed
+	default:
+		run.Status = StatusFinished
+	}
+	if err != nil {
+		run.Error = err.Error()
+	}
+	if result != nil {
+		run.Progress.Checked = result.Visited
+		run.DeadLinks = len(result.DeadLinks)
+	}
+
+	if metrics != nil {
+		r.Metrics.finishRun(metrics, run)
+	}
+
+	if saveErr := r.Store.Save(run); saveErr != nil {
+		log.Println("Error saving run", run.ID+":", saveErr)
+	}
+	log.Println("Run", run.ID, run.Status+":", run.DeadLinks, "dead links")
+
+	return run, result, err
+}
+
+// Get returns the run with the given ID, with the latest progress of runs in progress
+func (r *Runner) Get(id string) (Run, error) {
+	r.mu.Lock()
+	active, ok := r.active[id]
+	var run Run
+	if ok {
+		run = active.run
+	}
+	r.mu.Unlock()
+	if ok {
+		return run, nil
+	}
+
+	return r.Store.Get(id)
+}
+
+// List returns every run, the most recent first, with the latest progress of runs in progress
+func (r *Runner) List() ([]Run, error) {
+	runs, err := r.Store.List()
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, run := range runs {
+		if active, ok := r.active[run.ID]; ok {
+			runs[i] = active.run
+		}
+	}
+	return runs, nil
+}
+
+// Cancel stops a run in progress, which then writes the reports for what it found so far
+func (r *Runner) Cancel(id string) error {
+	r.mu.Lock()
+	active, ok := r.active[id]
+	r.mu.Unlock()
+	if ok {
+		active.cancel()
+		return nil
+	}
+
+	// Tell apart runs that are over from runs that never existed
+	if _, err := r.Store.Get(id); err != nil {
+		return err
+	}
+	return ErrNotRunning
+}
+
+// Shutdown cancels every run in progress and waits for them to save their results
+func (r *Runner) Shutdown() {
+	r.mu.Lock()
+	for _, active := range r.active {
+		active.cancel()
+	}
+	r.mu.Unlock()
+
+	r.wg.Wait()
+}
+
+// Observer that counts the progress of a run for status requests
+type progressObserver struct {
+	runner *Runner
+	id     string
+}
+
+// Updates the run while it is active
+func (o *progressObserver) update(fn func(run *Run)) {
+	o.runner.mu.Lock()
+	defer o.runner.mu.Unlock()
+
+	if active, ok := o.runner.active[o.id]; ok {
+		fn(&active.run)
+	}
+}
+
+func (o *progressObserver) OnPageFetched(crawler.Page) {
+	o.update(func(run *Run) { run.Progress.Fetched++ })
+}
+
+func (o *progressObserver) OnLinkDiscovered(crawler.Link) {
+	o.update(func(run *Run) { run.Progress.Discovered++ })
+}
+
+func (o *progressObserver) OnLinkChecked(crawler.LinkCheck) {
+	o.update(func(run *Run) { run.Progress.Checked++ })
+}
+
+func (o *progressObserver) OnFinding(finding crawler.Finding) {
+	o.update(func(run *Run) {
+		run.Progress.Findings++
+		if finding.Kind == crawler.FindingDeadLink || finding.Kind == crawler.FindingSoft404 {
+			run.DeadLinks++
+		}
+	})
+}
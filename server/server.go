@@ -0,0 +1,196 @@
+// Package server runs crawls in the background and exposes them over a REST API, so a crawl
+// can be started and followed from a browser instead of a shell:
+//
+//	GET  /profiles                     names of the profiles runs can use
+//	GET  /runs                         every run, the most recent first
+//	POST /runs                         start a run, the body picks {"profile": ..., "engine": ...}
+//	GET  /runs/{id}                    status and progress of a run
+//	POST /runs/{id}/cancel             stop a run, keeping what it found so far
+//	GET  /runs/{id}/reports/{name}     a report file of the run, such as dead_links.txt
+//	GET  /metrics                      Prometheus metrics, when the runner has Metrics
+//
+// Runs and their reports are kept on disk by a Store, so past runs survive restarts.
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// Largest request body the API reads, start requests are tiny
+const maxRequestSize = 1 << 20
+
+// StartRequest is the body of a request to start a run, both fields are optional
+type StartRequest struct {
+	Profile string `json:"profile"`
+	Engine  string `json:"engine"`
+}
+
+// NewHandler returns the REST API for the runs of runner
+func NewHandler(runner *Runner) http.Handler {
+	h := &handler{runner: runner}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/profiles", h.profiles)
+	mux.HandleFunc("/runs", h.runs)
+	mux.HandleFunc("/runs/", h.run)
+	if runner.Metrics != nil {
+		mux.Handle("/metrics", runner.Metrics)
+	}
+	return mux
+}
+
+// Serves the API endpoints
+type handler struct {
+	runner *Runner
+}
+
+// GET /profiles
+func (h *handler) profiles(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	names, err := h.runner.Profiles.Names()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, names)
+}
+
+// GET and POST /runs
+func (h *handler) runs(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		runs, err := h.runner.List()
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, runs)
+
+	case http.MethodPost:
+		var req StartRequest
+		body := http.MaxBytesReader(w, r.Body, maxRequestSize)
+		if err := json.NewDecoder(body).Decode(&req); err != nil && err != io.EOF {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+			return
+		}
+
+		run, err := h.runner.Start(req.Profile, req.Engine)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		w.Header().Set("Location", "/runs/"+run.ID)
+		writeJSON(w, http.StatusAccepted, run)
+
+	default:
+		allowMethod(w, r, http.MethodGet, http.MethodPost)
+	}
+}
+
+// Everything under /runs/: a run, its cancellation and its reports
+func (h *handler) run(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/runs/"), "/")
+	id := parts[0]
+
+	switch {
+	case len(parts) == 1:
+		if !allowMethod(w, r, http.MethodGet) {
+			return
+		}
+		run, err := h.runner.Get(id)
+		if err != nil {
+			writeError(w, statusFor(err), err)
+			return
+		}
+		writeJSON(w, http.StatusOK, run)
+
+	case len(parts) == 2 && parts[1] == "cancel":
+		if !allowMethod(w, r, http.MethodPost) {
+			return
+		}
+		if err := h.runner.Cancel(id); err != nil {
+			writeError(w, statusFor(err), err)
+			return
+		}
+		run, err := h.runner.Get(id)
+		if err != nil {
+			writeError(w, statusFor(err), err)
+			return
+		}
+		writeJSON(w, http.StatusAccepted, run)
+
+	case len(parts) == 3 && parts[1] == "reports":
+		if !allowMethod(w, r, http.MethodGet) {
+			return
+		}
+		if _, err := h.runner.Get(id); err != nil {
+			writeError(w, statusFor(err), err)
+			return
+		}
+		path, err := h.runner.Store.ReportPath(id, parts[2])
+		if err != nil {
+			writeError(w, http.StatusNotFound, fmt.Errorf("report not found: %s", parts[2]))
+			return
+		}
+
+		// The reports are plain text, apart from the dead links kept as JSON
+		if strings.HasSuffix(path, ".json") {
+			w.Header().Set("Content-Type", "application/json")
+		} else {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		}
+		http.ServeFile(w, r, path)
+
+	default:
+		writeError(w, http.StatusNotFound, errors.New("not found"))
+	}
+}
+
+// Answers 405 unless the request uses one of the allowed methods
+func allowMethod(w http.ResponseWriter, r *http.Request, methods ...string) bool {
+	for _, method := range methods {
+		if r.Method == method {
+			return true
+		}
+	}
+
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+	return false
+}
+
+// Picks the status code for an error of the runner or the store
+func statusFor(err error) int {
+	switch {
+	case errors.Is(err, ErrRunNotFound), errors.Is(err, os.ErrNotExist):
+		return http.StatusNotFound
+	case errors.Is(err, ErrNotRunning):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// Writes v as the JSON body of the response
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(v)
+}
+
+// Writes an error as a JSON body: {"error": "..."}
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
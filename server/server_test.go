@@ -0,0 +1,215 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Creates a runner for the site at siteURL with a "fast" profile that skips robots.txt and rate limits
+func newTestRunner(t *testing.T, siteURL string) *Runner {
+	t.Helper()
+
+	profiles := t.TempDir()
+	profile := `{"hosts": [{"domain_glob": "*", "ignore_robots": true}]}`
+	if err := os.WriteFile(filepath.Join(profiles, "fast.json"), []byte(profile), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := OpenStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("OpenStore() error = %v", err)
+	}
+
+	runner := &Runner{
+		Store:    store,
+		Profiles: Profiles{Dir: profiles},
+		Domain:   siteURL + "/",
+		HomeURL:  siteURL + "/index.html",
+	}
+	t.Cleanup(runner.Shutdown)
+	return runner
+}
+
+// Sends a request to the API and decodes its JSON answer into v, returning the status code
+func doJSON(t *testing.T, api http.Handler, method, path, body string, v interface{}) int {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	api.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+	if v != nil {
+		if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+			t.Fatalf("%s %s answered %q: %v", method, path, rec.Body.String(), err)
+		}
+	}
+	return rec.Code
+}
+
+// Polls the run until it is over
+func waitForRun(t *testing.T, api http.Handler, id string) Run {
+	t.Helper()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		var run Run
+		if code := doJSON(t, api, http.MethodGet, "/runs/"+id, "", &run); code != http.StatusOK {
+			t.Fatalf("GET /runs/%s status = %d, want %d", id, code, http.StatusOK)
+		}
+		if run.Status != StatusRunning {
+			return run
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatalf("run %s did not finish in time", id)
+	return Run{}
+}
+
+func TestHandler_run(t *testing.T) {
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/index.html":
+			fmt.Fprint(w, `<a href="page.html">Page</a> <a href="missing.html">Missing</a>`)
+		case "/page.html":
+			fmt.Fprint(w, "content")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer site.Close()
+
+	runner := newTestRunner(t, site.URL)
+	api := NewHandler(runner)
+
+	var profiles []string
+	doJSON(t, api, http.MethodGet, "/profiles", "", &profiles)
+	if want := []string{"default", "fast"}; !reflect.DeepEqual(profiles, want) {
+		t.Errorf("GET /profiles = %v, want %v", profiles, want)
+	}
+
+	// Start a run and wait for it to finish
+	var started Run
+	if code := doJSON(t, api, http.MethodPost, "/runs", `{"profile": "fast"}`, &started); code != http.StatusAccepted {
+		t.Fatalf("POST /runs status = %d, want %d", code, http.StatusAccepted)
+	}
+	if started.Status != StatusRunning || started.Profile != "fast" || started.Engine != "custom" {
+		t.Errorf("POST /runs = %+v, want a running custom run with profile fast", started)
+	}
+
+	run := waitForRun(t, api, started.ID)
+	if run.Status != StatusFinished || run.DeadLinks != 1 || run.Progress.Checked != 3 {
+		t.Errorf("GET /runs/%s = %+v, want finished with 1 dead link and 3 URLs checked", run.ID, run)
+	}
+
+	// The reports are the same files the command line writes
+	rec := httptest.NewRecorder()
+	api.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/runs/"+run.ID+"/reports/dead_links.txt", nil))
+	want := "dead link " + site.URL + "/missing.html found at: " + site.URL + "/index.html\n"
+	if body, _ := io.ReadAll(rec.Body); rec.Code != http.StatusOK || string(body) != want {
+		t.Errorf("GET dead_links.txt = %d %q, want %d %q", rec.Code, body, http.StatusOK, want)
+	}
+
+	// The run is listed, and it cannot be cancelled anymore
+	var runs []Run
+	doJSON(t, api, http.MethodGet, "/runs", "", &runs)
+	if len(runs) != 1 || runs[0].ID != run.ID {
+		t.Errorf("GET /runs = %+v, want only run %s", runs, run.ID)
+	}
+	if code := doJSON(t, api, http.MethodPost, "/runs/"+run.ID+"/cancel", "", nil); code != http.StatusConflict {
+		t.Errorf("POST cancel of a finished run status = %d, want %d", code, http.StatusConflict)
+	}
+}
+
+func TestHandler_cancel(t *testing.T) {
+	// Every page links to the next one, forever, slowly
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+		fmt.Fprintf(w, `<a href="%s/next">Next</a>`, r.URL.Path)
+	}))
+	defer site.Close()
+
+	api := NewHandler(newTestRunner(t, site.URL))
+
+	var started Run
+	doJSON(t, api, http.MethodPost, "/runs", `{"profile": "fast", "engine": "colly"}`, &started)
+	time.Sleep(100 * time.Millisecond)
+
+	if code := doJSON(t, api, http.MethodPost, "/runs/"+started.ID+"/cancel", "", nil); code != http.StatusAccepted {
+		t.Fatalf("POST cancel status = %d, want %d", code, http.StatusAccepted)
+	}
+	if run := waitForRun(t, api, started.ID); run.Status != StatusCancelled || run.Progress.Checked == 0 {
+		t.Errorf("cancelled run = %+v, want cancelled after checking some URLs", run)
+	}
+}
+
+func TestHandler_errors(t *testing.T) {
+	api := NewHandler(newTestRunner(t, "http://localhost"))
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		want   int
+	}{
+		{
+			name:   "Unknown profile",
+			method: http.MethodPost,
+			path:   "/runs",
+			body:   `{"profile": "nightly"}`,
+			want:   http.StatusBadRequest,
+		},
+		{
+			name:   "Unknown engine",
+			method: http.MethodPost,
+			path:   "/runs",
+			body:   `{"engine": "wget"}`,
+			want:   http.StatusBadRequest,
+		},
+		{
+			name:   "Invalid body",
+			method: http.MethodPost,
+			path:   "/runs",
+			body:   `{"profile":`,
+			want:   http.StatusBadRequest,
+		},
+		{
+			name:   "Unknown run",
+			method: http.MethodGet,
+			path:   "/runs/20240101-000000",
+			want:   http.StatusNotFound,
+		},
+		{
+			name:   "Report of an unknown run",
+			method: http.MethodGet,
+			path:   "/runs/20240101-000000/reports/dead_links.txt",
+			want:   http.StatusNotFound,
+		},
+		{
+			name:   "Wrong method",
+			method: http.MethodDelete,
+			path:   "/runs",
+			want:   http.StatusMethodNotAllowed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body map[string]string
+			if got := doJSON(t, api, tt.method, tt.path, tt.body, &body); got != tt.want {
+				t.Errorf("%s %s status = %d, want %d", tt.method, tt.path, got, tt.want)
+			}
+			if body["error"] == "" {
+				t.Errorf("%s %s answered %v, want an error", tt.method, tt.path, body)
+			}
+		})
+	}
+}
@@ -0,0 +1,268 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/underground-software/KDLP_Webcrawler.git/crawler"
+)
+
+// RunStatus tells where a run is at
+type RunStatus string
+
+const (
+	// The crawl is going on
+	StatusRunning RunStatus = "running"
+
+	// The crawl checked everything it could reach or ran out of budget
+	StatusFinished RunStatus = "finished"
+
+	// The crawl was cancelled, its reports cover what it found until then
+	StatusCancelled RunStatus = "cancelled"
+
+	// The crawl could not be run
+	StatusFailed RunStatus = "failed"
+
+	// The crawl was still running when the process that ran it stopped
+	StatusInterrupted RunStatus = "interrupted"
+)
+
+// Run is one crawl, kept in the store with its reports
+type Run struct {
+	ID      string    `json:"id"`
+	Profile string    `json:"profile"`
+	Engine  string    `json:"engine"`
+	Status  RunStatus `json:"status"`
+	Started time.Time `json:"started"`
+
+	// Set once the run is over
+	Finished *time.Time `json:"finished,omitempty"`
+
+	// Why the run failed or stopped early
+	Error string `json:"error,omitempty"`
+
+	Progress Progress `json:"progress"`
+
+	// Number of dead links found, final once the run is over
+	DeadLinks int `json:"dead_links"`
+
+	// Report files of the run, see Store.ReportPath
+	Reports []string `json:"reports,omitempty"`
+}
+
+// Progress counts what a run did so far
+type Progress struct {
+	Checked    int `json:"checked"`
+	Fetched    int `json:"pages_fetched"`
+	Discovered int `json:"links_discovered"`
+	Findings   int `json:"findings"`
+}
+
+// ErrRunNotFound is returned for IDs the store has no run for
+var ErrRunNotFound = errors.New("run not found")
+
+// Name of the file that holds a run's metadata in its directory
+const runFile = "run.json"
+
+// Name of the file that holds a run's dead links as JSON in its directory
+const deadLinksFile = "dead_links.json"
+
+// Store keeps every run in a directory of its own under Dir, with run.json
+// describing the run next to the report files the crawl wrote
+type Store struct {
+	dir string
+
+	// Guards the allocation of run IDs
+	mu sync.Mutex
+}
+
+// OpenStore opens the store in dir, creating the directory if needed. Runs that were still
+// running when the store was last used cannot finish anymore, they are marked interrupted.
+func OpenStore(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	s := &Store{dir: dir}
+
+	runs, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	for _, run := range runs {
+		if run.Status == StatusRunning {
+			run.Status = StatusInterrupted
+			if err := s.Save(run); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return s, nil
+}
+
+// Create adds a new running run for the given profile and engine
+func (s *Store) Create(profile, engine string) (Run, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// IDs tell when a run was started, a suffix tells apart runs started within the same second
+	started := time.Now()
+	base := started.UTC().Format("20060102-150405")
+	id := base
+	for n := 2; ; n++ {
+		err := os.Mkdir(filepath.Join(s.dir, id), 0755)
+		if err == nil {
+			break
+		}
+		if !os.IsExist(err) {
+			return Run{}, err
+		}
+		id = base + "-" + strconv.Itoa(n)
+	}
+
+	run := Run{ID: id, Profile: profile, Engine: engine, Status: StatusRunning, Started: started}
+	return run, s.Save(run)
+}
+
+// Save writes the run's metadata, listing the report files found in its directory
+func (s *Store) Save(run Run) error {
+	entries, err := os.ReadDir(s.Dir(run.ID))
+	if err != nil {
+		return err
+	}
+	run.Reports = nil
+	for _, entry := range entries {
+		if !entry.IsDir() && entry.Name() != runFile {
+			run.Reports = append(run.Reports, entry.Name())
+		}
+	}
+
+	data, err := json.MarshalIndent(run, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first, so readers never see a half written run
+	path := filepath.Join(s.Dir(run.ID), runFile)
+	if err := os.WriteFile(path+".tmp", data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// Get returns the run with the given ID
+func (s *Store) Get(id string) (Run, error) {
+	if !validName(id) {
+		return Run{}, ErrRunNotFound
+	}
+
+	data, err := os.ReadFile(filepath.Join(s.Dir(id), runFile))
+	if os.IsNotExist(err) {
+		return Run{}, ErrRunNotFound
+	}
+	if err != nil {
+		return Run{}, err
+	}
+
+	var run Run
+	if err := json.Unmarshal(data, &run); err != nil {
+		return Run{}, fmt.Errorf("failed to read run %s: %w", id, err)
+	}
+	return run, nil
+}
+
+// List returns every run in the store, the most recent first
+func (s *Store) List() ([]Run, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	runs := []Run{}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		run, err := s.Get(entry.Name())
+		if err == ErrRunNotFound {
+			// Not a run, or one whose metadata was never written
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+
+	sort.Slice(runs, func(i, j int) bool {
+		if !runs[i].Started.Equal(runs[j].Started) {
+			return runs[i].Started.After(runs[j].Started)
+		}
+		return runs[i].ID > runs[j].ID
+	})
+	return runs, nil
+}
+
+// Dir returns the directory the run's report files are written to
+func (s *Store) Dir(id string) string {
+	return filepath.Join(s.dir, id)
+}
+
+// ReportPath returns the path of one of the run's report files, such as dead_links.txt
+func (s *Store) ReportPath(id, name string) (string, error) {
+	if !validName(id) {
+		return "", ErrRunNotFound
+	}
+	if !validName(name) || name == runFile {
+		return "", fmt.Errorf("invalid report name %q", name)
+	}
+
+	path := filepath.Join(s.Dir(id), name)
+	if _, err := os.Stat(path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// SaveDeadLinks writes the dead links a run found as JSON next to its other reports
+func (s *Store) SaveDeadLinks(id string, links []crawler.DeadLink) error {
+	data, err := json.MarshalIndent(links, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(s.Dir(id), deadLinksFile), data, 0644)
+}
+
+// DeadLinks returns the dead links a run found, none for runs that did not finish
+func (s *Store) DeadLinks(id string) ([]crawler.DeadLink, error) {
+	if !validName(id) {
+		return nil, ErrRunNotFound
+	}
+
+	data, err := os.ReadFile(filepath.Join(s.Dir(id), deadLinksFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var links []crawler.DeadLink
+	if err := json.Unmarshal(data, &links); err != nil {
+		return nil, fmt.Errorf("failed to read dead links of run %s: %w", id, err)
+	}
+	return links, nil
+}
+
+// Checks that a name from a request is a plain file name, so it cannot lead out of the store
+func validName(name string) bool {
+	return name != "" && !strings.HasPrefix(name, ".") && !strings.ContainsAny(name, `/\`)
+}
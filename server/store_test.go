@@ -0,0 +1,72 @@
+package server
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/underground-software/KDLP_Webcrawler.git/crawler"
+)
+
+func TestStore(t *testing.T) {
+	dir := t.TempDir()
+	store, err := OpenStore(dir)
+	if err != nil {
+		t.Fatalf("OpenStore() error = %v", err)
+	}
+
+	// Runs started within the same second still get IDs of their own
+	first, err := store.Create("default", "custom")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	second, err := store.Create("nightly", "colly")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if first.ID == second.ID {
+		t.Fatalf("Create() gave two runs the ID %s", first.ID)
+	}
+
+	links := []crawler.DeadLink{{URL: "https://kdlp.underground.software/missing.html", ReferringURL: "https://kdlp.underground.software/index.html", StatusCode: 404}}
+	if err := store.SaveDeadLinks(first.ID, links); err != nil {
+		t.Fatalf("SaveDeadLinks() error = %v", err)
+	}
+	first.Status = StatusFinished
+	if err := store.Save(first); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	got, err := store.Get(first.ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if got.Status != StatusFinished || !reflect.DeepEqual(got.Reports, []string{"dead_links.json"}) {
+		t.Errorf("Get() = %+v, want a finished run with dead_links.json", got)
+	}
+	if gotLinks, err := store.DeadLinks(first.ID); err != nil || !reflect.DeepEqual(gotLinks, links) {
+		t.Errorf("DeadLinks() = %v, %v, want %v", gotLinks, err, links)
+	}
+
+	// Reopening the store interrupts the runs that were left running
+	store, err = OpenStore(dir)
+	if err != nil {
+		t.Fatalf("OpenStore() error = %v", err)
+	}
+	runs, err := store.List()
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+
+	var statuses []string
+	for _, run := range runs {
+		statuses = append(statuses, run.ID+" "+string(run.Status))
+	}
+	want := []string{second.ID + " interrupted", first.ID + " finished"}
+	if !reflect.DeepEqual(statuses, want) {
+		t.Errorf("List() = %v, want %v", statuses, want)
+	}
+
+	if _, err := store.Get("../" + first.ID); err != ErrRunNotFound {
+		t.Errorf("Get() outside the store error = %v, want %v", err, ErrRunNotFound)
+	}
+}
@@ -0,0 +1,143 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/underground-software/KDLP_Webcrawler.git/crawler"
+)
+
+// SignatureHeader is the header of signed webhook posts. It holds "sha256=" followed by the
+// hex HMAC-SHA256 of the body keyed with the webhook secret, so receivers can check who sent it.
+const SignatureHeader = "X-Webcrawler-Signature"
+
+// WebhookPayload is the JSON body of a webhook post: the change, with its text when there is a template
+type WebhookPayload struct {
+	Change
+
+	// Change rendered by the webhook's template, the field chat systems like Slack and Mattermost show
+	Text string `json:"text,omitempty"`
+}
+
+// WebhookNotifier posts changes with new broken links as JSON to URL. Changes where broken
+// links were only fixed are not posted.
+//
+// It is also a crawler.Reporter, posting every dead link a single crawl found as new.
+type WebhookNotifier struct {
+	URL string
+
+	// Key to sign posts with, see SignatureHeader. Posts are not signed if empty.
+	Secret string
+
+	// Renders the text of the payload from the change, no text if nil
+	Template *template.Template
+
+	// Number of times a failed post is tried again. Posts are only tried again when the
+	// receiver could not be reached or answered with a server error or 429.
+	Retries int
+
+	// Wait before the first retry, doubled before each of the next ones. One second if zero.
+	RetryWait time.Duration
+
+	// Client to post with, one with a 30 second timeout if nil
+	Client *http.Client
+}
+
+// Notify posts the change if it has new broken links, trying again as configured
+func (n *WebhookNotifier) Notify(ctx context.Context, change Change) error {
+	if len(change.New) == 0 {
+		return nil
+	}
+
+	payload := WebhookPayload{Change: change}
+	if n.Template != nil {
+		var text strings.Builder
+		if err := n.Template.Execute(&text, change); err != nil {
+			return fmt.Errorf("failed to render webhook template: %w", err)
+		}
+		payload.Text = text.String()
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	wait := n.RetryWait
+	if wait <= 0 {
+		wait = time.Second
+	}
+	for attempt := 0; ; attempt++ {
+		retry, err := n.post(ctx, body)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt >= n.Retries {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(wait):
+		}
+		wait *= 2
+	}
+}
+
+// Report posts the dead links of a crawl that ran on its own, with no previous run to compare with
+func (n *WebhookNotifier) Report(result *crawler.Result) error {
+	finished := time.Now()
+	run := Run{Status: StatusFinished, Started: finished.Add(-result.Elapsed), Finished: &finished, DeadLinks: len(result.DeadLinks)}
+	change := Change{Run: run, DeadLinks: result.DeadLinks, New: result.DeadLinks, Fixed: []crawler.DeadLink{}}
+	return n.Notify(context.Background(), change)
+}
+
+// Sends the body once, telling whether a failure is worth trying again
+func (n *WebhookNotifier) post(ctx context.Context, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "KDLP_Webcrawler")
+	if n.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(n.Secret, body))
+	}
+
+	client := n.Client
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, fmt.Errorf("failed to post webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Drain the body so the connection can be reused by the retry
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+	return retry, fmt.Errorf("webhook %s answered %s", n.URL, resp.Status)
+}
+
+// Sign returns the value of SignatureHeader for a body signed with secret
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,134 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/underground-software/KDLP_Webcrawler.git/crawler"
+)
+
+// Webhook receiver that answers with the given status codes in turn, then 200
+type webhookReceiver struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	statuses []int
+	bodies   [][]byte
+	headers  []http.Header
+}
+
+func newWebhookReceiver(t *testing.T, statuses ...int) *webhookReceiver {
+	rec := &webhookReceiver{statuses: statuses}
+	rec.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		rec.mu.Lock()
+		defer rec.mu.Unlock()
+		rec.bodies = append(rec.bodies, body)
+		rec.headers = append(rec.headers, r.Header)
+		if len(rec.statuses) > 0 {
+			w.WriteHeader(rec.statuses[0])
+			rec.statuses = rec.statuses[1:]
+		}
+	}))
+	t.Cleanup(rec.Close)
+	return rec
+}
+
+func TestWebhookNotifier_Notify(t *testing.T) {
+	link := crawler.DeadLink{URL: "https://kdlp.underground.software/a.html", ReferringURL: "https://kdlp.underground.software/index.html", StatusCode: 404}
+	fixed := crawler.DeadLink{URL: "https://kdlp.underground.software/b.html", ReferringURL: "https://kdlp.underground.software/index.html", StatusCode: 404}
+	change := Change{Run: Run{ID: "20240101-000000"}, DeadLinks: []crawler.DeadLink{link}, New: []crawler.DeadLink{link}, Fixed: []crawler.DeadLink{fixed}}
+	text := template.Must(template.New("text").Parse(`{{len .New}} new broken links in run {{.Run.ID}}`))
+
+	tests := []struct {
+		name      string
+		statuses  []int
+		change    Change
+		wantPosts int
+		wantErr   bool
+	}{
+		{name: "Delivered", change: change, wantPosts: 1},
+		{name: "Retried after a server error", statuses: []int{503, 502}, change: change, wantPosts: 3},
+		{name: "Retried after rate limiting", statuses: []int{429}, change: change, wantPosts: 2},
+		{name: "Out of retries", statuses: []int{500, 500, 500, 500}, change: change, wantPosts: 3, wantErr: true},
+		{name: "Rejected", statuses: []int{400}, change: change, wantPosts: 1, wantErr: true},
+		{name: "Only fixed links", change: Change{Run: change.Run, New: []crawler.DeadLink{}, Fixed: []crawler.DeadLink{fixed}}, wantPosts: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			receiver := newWebhookReceiver(t, tt.statuses...)
+			notifier := &WebhookNotifier{URL: receiver.URL, Secret: "s3cret", Template: text, Retries: 2, RetryWait: time.Millisecond}
+
+			err := notifier.Notify(context.Background(), tt.change)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Notify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(receiver.bodies) != tt.wantPosts {
+				t.Fatalf("Notify() posted %d times, want %d", len(receiver.bodies), tt.wantPosts)
+			}
+			if tt.wantPosts == 0 {
+				return
+			}
+
+			// Every post is the same signed payload
+			body, header := receiver.bodies[len(receiver.bodies)-1], receiver.headers[len(receiver.headers)-1]
+			if got, want := header.Get(SignatureHeader), Sign("s3cret", body); got != want {
+				t.Errorf("post %s = %q, want %q", SignatureHeader, got, want)
+			}
+			if got := header.Get("Content-Type"); got != "application/json" {
+				t.Errorf("post Content-Type = %q, want application/json", got)
+			}
+
+			var payload WebhookPayload
+			if err := json.Unmarshal(body, &payload); err != nil {
+				t.Fatalf("post body %q: %v", body, err)
+			}
+			if want := "1 new broken links in run 20240101-000000"; payload.Text != want {
+				t.Errorf("post text = %q, want %q", payload.Text, want)
+			}
+			if payload.Run.ID != change.Run.ID || !reflect.DeepEqual(payload.New, change.New) || !reflect.DeepEqual(payload.Fixed, change.Fixed) {
+				t.Errorf("post payload = %+v, want %+v", payload.Change, change)
+			}
+		})
+	}
+}
+
+func TestWebhookNotifier_Report(t *testing.T) {
+	receiver := newWebhookReceiver(t)
+	notifier := &WebhookNotifier{URL: receiver.URL}
+
+	// Without dead links there is nothing to post
+	if err := notifier.Report(&crawler.Result{}); err != nil {
+		t.Fatalf("Report() error = %v", err)
+	}
+	if len(receiver.bodies) != 0 {
+		t.Fatalf("Report() without dead links posted %d times, want 0", len(receiver.bodies))
+	}
+
+	// Every dead link of a crawl on its own is new
+	links := []crawler.DeadLink{{URL: "https://kdlp.underground.software/a.html", StatusCode: 404}}
+	if err := notifier.Report(&crawler.Result{DeadLinks: links}); err != nil {
+		t.Fatalf("Report() error = %v", err)
+	}
+	if len(receiver.bodies) != 1 {
+		t.Fatalf("Report() posted %d times, want 1", len(receiver.bodies))
+	}
+
+	var payload WebhookPayload
+	if err := json.Unmarshal(receiver.bodies[0], &payload); err != nil {
+		t.Fatalf("post body %q: %v", receiver.bodies[0], err)
+	}
+	if !reflect.DeepEqual(payload.New, links) || payload.Text != "" || receiver.headers[0].Get(SignatureHeader) != "" {
+		t.Errorf("Report() posted %s, want the dead links unsigned and without text", receiver.bodies[0])
+	}
+}